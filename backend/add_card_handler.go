@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// addCardHandler deals the current user another card, up to the theme's
+// limit, until the game starts.
+func addCardHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	theme, found := getThemeByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	if theme.cardsLocked() {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "The game has started, cards are locked"})
+	}
+
+	holderID, err := theme.holderID(user)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	if limit := theme.maxCardsPerPlayer(); len(theme.userCards(holderID)) >= limit {
+		return c.JSON(http.StatusConflict, map[string]string{"error": fmt.Sprintf("You can hold at most %d cards in this theme", limit)})
+	}
+
+	card, err := theme.NewCard(user)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := store.SaveCard(card); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventCardCreated, user, theme.ID, card.ID, nil, card)
+
+	broadcastThemeUpdate(theme.ID, "card_updated", card)
+
+	return c.JSON(http.StatusCreated, card)
+}
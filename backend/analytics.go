@@ -0,0 +1,246 @@
+package main
+
+import (
+	"cmp"
+	"math/rand/v2"
+	"slices"
+)
+
+// ItemImpact describes what calling one item would do to a theme's cards.
+type ItemImpact struct {
+	ItemID string `json:"item_id"`
+	Name   string `json:"name"`
+	Marked bool   `json:"marked"`
+	// Cards counts the active cards holding the item
+	Cards int `json:"cards"`
+	// Completes counts the cards that calling the item would make win
+	Completes int `json:"completes"`
+}
+
+// WinChance is a player's, or a team's, estimated chance of being among
+// the next winners.
+type WinChance struct {
+	UserID      string  `json:"user_id,omitempty"`
+	TeamID      string  `json:"team_id,omitempty"`
+	Name        string  `json:"name"`
+	Cards       int     `json:"cards"`
+	Probability float64 `json:"probability"`
+}
+
+// ThemeAnalytics is the admin's view of how the rest of a game could play
+// out.
+type ThemeAnalytics struct {
+	ThemeID   string       `json:"theme_id"`
+	Called    int          `json:"called"`
+	Remaining int          `json:"remaining"`
+	Items     []ItemImpact `json:"items"`
+	Players   []WinChance  `json:"players"`
+	// NoWinProbability is the share of simulations in which calling every
+	// remaining item produced no new winner
+	NoWinProbability float64 `json:"no_win_probability"`
+	Simulations      int     `json:"simulations"`
+	Seed             Seed    `json:"seed"`
+}
+
+// simCard is a card reduced to bitmasks for simulation.
+type simCard struct {
+	cardID   string
+	holderID string
+	marked   uint64
+	cells    map[string]uint64
+	shapes   []uint64
+}
+
+// analysis is a theme's analytics before simulating, holding copies of
+// everything the simulations need so they can run without dbMutex.
+type analysis struct {
+	ThemeAnalytics
+	cards []*simCard
+	pool  []string
+	// holders are the players and teams with cards in play, in the order
+	// their first card was found
+	holders []*WinChance
+}
+
+// simCards prepares the theme's active cards that have not won yet. Items
+// count as marked once called, whether or not they were daubed, since the
+// analytics look at what calling items makes possible.
+func (t *Theme) simCards() []*simCard {
+	called := make(map[string]bool)
+	for _, item := range t.Items {
+		if item.Marked {
+			called[item.ID] = true
+		}
+	}
+
+	var cards []*simCard
+	for _, card := range t.Cards {
+		if !card.active() || card.IsWinner {
+			continue
+		}
+
+		size := len(card.Items)
+		sc := &simCard{cardID: card.ID, holderID: card.holderID(), cells: make(map[string]uint64)}
+		for row := range size {
+			for col := range size {
+				id := card.Items[row][col]
+				bit := cellBit(size, row, col)
+				if id == freeSpaceID || called[id] {
+					sc.marked |= bit
+				} else {
+					sc.cells[id] |= bit
+				}
+			}
+		}
+		for _, pattern := range t.winPatterns() {
+			sc.shapes = append(sc.shapes, pattern.shapes(size)...)
+		}
+		cards = append(cards, sc)
+	}
+
+	// Map iteration is random; keep simulations reproducible for a seed
+	slices.SortFunc(cards, func(a, b *simCard) int {
+		return cmp.Compare(a.cardID, b.cardID)
+	})
+
+	return cards
+}
+
+// wins reports whether the card wins with marked cells.
+func (sc *simCard) wins(marked uint64) bool {
+	for _, shape := range sc.shapes {
+		if marked&shape == shape {
+			return true
+		}
+	}
+	return false
+}
+
+// prepareAnalytics reports item coverage and impact and copies what the
+// simulations need out of the theme. Callers hold dbMutex.
+func (t *Theme) prepareAnalytics() *analysis {
+	an := &analysis{
+		ThemeAnalytics: ThemeAnalytics{
+			ThemeID: t.ID,
+			Items:   []ItemImpact{},
+			Players: []WinChance{},
+		},
+		cards: t.simCards(),
+	}
+
+	for _, item := range t.Items {
+		impact := ItemImpact{ItemID: item.ID, Name: item.Name, Marked: item.Marked}
+		for _, card := range t.Cards {
+			if card.active() && card.hasItem(item.ID) {
+				impact.Cards++
+			}
+		}
+		if item.Marked {
+			an.Called++
+		} else {
+			an.Remaining++
+			an.pool = append(an.pool, item.ID)
+			for _, sc := range an.cards {
+				if cells, ok := sc.cells[item.ID]; ok && sc.wins(sc.marked|cells) {
+					impact.Completes++
+				}
+			}
+		}
+		an.Items = append(an.Items, impact)
+	}
+
+	usernames := make(map[string]string, len(db.Users))
+	for _, user := range db.Users {
+		usernames[user.ID] = user.Username
+	}
+
+	// Credit wins to whoever holds the card: a team or a player
+	holders := make(map[string]*WinChance)
+	for _, sc := range an.cards {
+		chance, ok := holders[sc.holderID]
+		if !ok {
+			card := t.Cards[sc.cardID]
+			chance = &WinChance{TeamID: card.TeamID}
+			if card.TeamID == "" {
+				chance.UserID = card.UserID
+			}
+			if team, ok := t.getTeam(card.TeamID); ok {
+				chance.Name = team.Name
+			} else {
+				chance.Name = usernames[chance.UserID]
+			}
+			holders[sc.holderID] = chance
+			an.holders = append(an.holders, chance)
+		}
+		chance.Cards++
+	}
+
+	return an
+}
+
+// simulate runs simulations random orderings of the uncalled items to
+// estimate who wins next. It only uses the copies made by prepareAnalytics,
+// so callers need not hold dbMutex.
+func (an *analysis) simulate(simulations int, seed Seed) ThemeAnalytics {
+	a := an.ThemeAnalytics
+	a.Simulations = simulations
+	a.Seed = seed
+
+	wins := make(map[string]int)
+	noWin := 0
+	if simulations > 0 && len(an.cards) > 0 {
+		rng := rand.New(rand.NewPCG(uint64(seed), uint64(len(an.pool))))
+		draw := slices.Clone(an.pool)
+		marked := make([]uint64, len(an.cards))
+		winners := make(map[string]bool)
+
+		for range simulations {
+			rng.Shuffle(len(draw), func(i, j int) { draw[i], draw[j] = draw[j], draw[i] })
+			for i, sc := range an.cards {
+				marked[i] = sc.marked
+			}
+			clear(winners)
+
+			for _, itemID := range draw {
+				for i, sc := range an.cards {
+					cells, ok := sc.cells[itemID]
+					if !ok {
+						continue
+					}
+					marked[i] |= cells
+					if sc.wins(marked[i]) {
+						winners[sc.holderID] = true
+					}
+				}
+				if len(winners) > 0 {
+					break
+				}
+			}
+
+			if len(winners) == 0 {
+				noWin++
+			}
+			for id := range winners {
+				wins[id]++
+			}
+		}
+	}
+
+	a.Players = make([]WinChance, 0, len(an.holders))
+	for _, chance := range an.holders {
+		player := *chance
+		if simulations > 0 {
+			player.Probability = float64(wins[cmp.Or(player.TeamID, player.UserID)]) / float64(simulations)
+		}
+		a.Players = append(a.Players, player)
+	}
+	slices.SortStableFunc(a.Players, func(x, y WinChance) int {
+		return cmp.Compare(y.Probability, x.Probability)
+	})
+
+	if simulations > 0 {
+		a.NoWinProbability = float64(noWin) / float64(simulations)
+	}
+
+	return a
+}
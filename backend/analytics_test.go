@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// newAnalyticsTheme returns a live theme where a is called and u1's card
+// needs one fewer item than u2's.
+func newAnalyticsTheme() *Theme {
+	theme := newTestTheme("t1", 3, 9)
+	theme.setState(ThemeLive)
+	theme.Items[0].Marked = true
+	theme.Cards["c1"] = &Card{ID: "c1", UserID: "u1", ThemeID: "t1", Items: [][]string{{"a", "b", "c"}, {"d", freeSpaceID, "e"}, {"f", "g", "h"}}}
+	theme.Cards["c2"] = &Card{ID: "c2", UserID: "u2", ThemeID: "t1", Items: [][]string{{"i", "b", "c"}, {"d", freeSpaceID, "e"}, {"f", "g", "h"}}}
+	return theme
+}
+
+func TestAnalytics(t *testing.T) {
+	useTestStore(t)
+
+	db.Users = append(db.Users, &User{ID: "u1", Username: "alice"}, &User{ID: "u2", Username: "bob"})
+	theme := newAnalyticsTheme()
+
+	a := theme.prepareAnalytics().simulate(2000, 42)
+	if a.Called != 1 || a.Remaining != 8 {
+		t.Errorf("called %d and remaining %d, want 1 and 8", a.Called, a.Remaining)
+	}
+	for _, item := range a.Items {
+		// h completes u1's diagonal through the called a
+		if item.ItemID == "h" && (item.Completes != 1 || item.Cards != 2) {
+			t.Errorf("impact of h = %+v, want on 2 cards completing 1", item)
+		}
+	}
+
+	if len(a.Players) != 2 || a.Players[0].UserID != "u1" || a.Players[0].Name != "alice" {
+		t.Fatalf("players = %+v, want alice most likely to win", a.Players)
+	}
+	if a.Players[0].Probability <= a.Players[1].Probability {
+		t.Errorf("alice's chance %g is not above bob's %g", a.Players[0].Probability, a.Players[1].Probability)
+	}
+
+	again := theme.prepareAnalytics().simulate(2000, 42)
+	if again.Players[0].Probability != a.Players[0].Probability {
+		t.Error("the same seed gave different estimates")
+	}
+}
+
+func TestAnalyticsWithoutSimulations(t *testing.T) {
+	useTestStore(t)
+
+	a := newAnalyticsTheme().prepareAnalytics().simulate(0, 42)
+	if a.Simulations != 0 || a.NoWinProbability != 0 {
+		t.Errorf("analytics = %+v, want no simulations run", a)
+	}
+	for _, player := range a.Players {
+		if player.Probability != 0 {
+			t.Errorf("player %s has chance %g without simulations", player.UserID, player.Probability)
+		}
+	}
+}
+
+func TestThemeAnalyticsHandler(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "admin-discord"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	db.Themes = append(db.Themes, newAnalyticsTheme())
+
+	server := newTestServer(t)
+	token := testToken(t, admin.ID)
+	url := server.URL + "/api/admin/themes/t1/analytics"
+
+	code, body := doRequest(t, http.MethodGet, url+"?simulations=0&seed=18446744073709551615", token, "")
+	if code != http.StatusOK {
+		t.Fatalf("analytics = %d %s", code, body)
+	}
+	var a ThemeAnalytics
+	if err := json.Unmarshal([]byte(body), &a); err != nil {
+		t.Fatal(err)
+	}
+	if a.Simulations != 0 {
+		t.Errorf("ran %d simulations, want 0", a.Simulations)
+	}
+	// The seed is a string so JavaScript does not round it
+	if !strings.Contains(body, `"seed":"18446744073709551615"`) {
+		t.Errorf("seed not sent as a string: %s", body)
+	}
+
+	if code, _ := doRequest(t, http.MethodGet, url, token, ""); code != http.StatusOK {
+		t.Errorf("analytics with default simulations = %d", code)
+	}
+	if code, _ := doRequest(t, http.MethodGet, url+"?simulations=-1", token, ""); code != http.StatusBadRequest {
+		t.Errorf("negative simulations = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, _ := doRequest(t, http.MethodGet, server.URL+"/api/admin/themes/missing/analytics", token, ""); code != http.StatusNotFound {
+		t.Errorf("analytics of a missing theme = %d, want %d", code, http.StatusNotFound)
+	}
+}
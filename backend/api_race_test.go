@@ -0,0 +1,231 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// TestAPIConcurrentRequests hammers the HTTP API from many players, an admin
+// and WebSocket listeners at once. Run it with -race to check that every
+// handler takes dbMutex.
+func TestAPIConcurrentRequests(t *testing.T) {
+	const players = 20
+
+	path := filepath.Join(t.TempDir(), "database.json")
+	p := newPersister(newJSONStore(path, 1), 10*time.Millisecond, 5)
+	go p.run()
+
+	d, err := p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	useDatabase(t, d, p)
+
+	admin := &User{ID: "admin", Username: "admin", DiscordID: "d-admin"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+
+	theme := newTestTheme("t1", 5, 30)
+	db.Themes = append(db.Themes, theme)
+	db.ActiveThemeID = theme.ID
+
+	tokens := make([]string, players)
+	for i := range players {
+		user := &User{ID: fmt.Sprint("u", i), Username: fmt.Sprint("player", i)}
+		db.Users = append(db.Users, user)
+		tokens[i] = testToken(t, user.ID)
+	}
+	adminToken := testToken(t, admin.ID)
+
+	server := newTestServer(t)
+	api := server.URL + "/api"
+
+	// Listeners that read every broadcast, in all rooms and in the default room
+	wsURL := "ws" + strings.TrimPrefix(server.URL, "http") + "/ws"
+	var listeners sync.WaitGroup
+	for _, query := range []string{"", "?room=" + defaultRoomID} {
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL+query, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer conn.Close()
+
+		listeners.Add(1)
+		go func() {
+			defer listeners.Done()
+			for {
+				if _, _, err := conn.ReadMessage(); err != nil {
+					return
+				}
+			}
+		}()
+	}
+
+	expect := func(method, url, token, body string, want int) {
+		if code, resp := doRequest(t, method, url, token, body); code != want {
+			t.Errorf("%s %s = %d %s, want %d", method, url, code, resp, want)
+		}
+	}
+
+	// Players are dealt cards while everyone reads the theme
+	var wg sync.WaitGroup
+	for i := range players {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			expect(http.MethodGet, api+"/themes/t1/cards/mine", tokens[i], "", http.StatusOK)
+		}()
+		go func() {
+			defer wg.Done()
+			expect(http.MethodGet, api+"/themes", tokens[i], "", http.StatusOK)
+		}()
+		go func() {
+			defer wg.Done()
+			expect(http.MethodGet, api+"/admin/themes/t1/cards", adminToken, "", http.StatusOK)
+		}()
+		go func() {
+			defer wg.Done()
+			expect(http.MethodGet, api+"/admin/themes/t1/analytics?simulations=10", adminToken, "", http.StatusOK)
+		}()
+	}
+	wg.Wait()
+
+	expect(http.MethodPost, api+"/admin/themes/t1/state", adminToken, `{"state":"live"}`, http.StatusOK)
+
+	// The admin calls items while players follow the game
+	for i := range players {
+		wg.Add(4)
+		go func() {
+			defer wg.Done()
+			expect(http.MethodPost, fmt.Sprintf("%s/admin/themes/t1/items/%s/toggle", api, theme.Items[i].ID), adminToken, "", http.StatusOK)
+		}()
+		go func() {
+			defer wg.Done()
+			expect(http.MethodGet, api+"/themes/t1/cards", tokens[i], "", http.StatusOK)
+		}()
+		go func() {
+			defer wg.Done()
+			expect(http.MethodGet, api+"/themes/t1/winners", tokens[i], "", http.StatusOK)
+		}()
+		go func() {
+			defer wg.Done()
+			expect(http.MethodGet, api+"/leaderboard", tokens[i], "", http.StatusOK)
+		}()
+	}
+	wg.Wait()
+
+	dbMutex.Lock()
+	closeAllConnections("test finished", time.Now().Add(time.Second))
+	dbMutex.Unlock()
+	listeners.Wait()
+
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := readDatabaseFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(saved.Themes[0].Cards); n != players {
+		t.Errorf("saved %d cards, want %d", n, players)
+	}
+	marked := 0
+	for _, item := range saved.Themes[0].Items {
+		if item.Marked {
+			marked++
+		}
+	}
+	if marked != players {
+		t.Errorf("saved %d marked items, want %d", marked, players)
+	}
+}
+
+// TestBroadcastDoesNotWaitForStalledClient checks that a client that stops
+// reading is dropped instead of blocking broadcasts made under dbMutex.
+func TestBroadcastDoesNotWaitForStalledClient(t *testing.T) {
+	useTestStore(t)
+
+	server := newTestServer(t)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	waitForClients(t, 1)
+
+	// Far more than the socket buffers and the send queue can hold
+	payload := strings.Repeat("x", 64<<10)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for range 2 * clientSendBuffer {
+			dbMutex.Lock()
+			broadcastUpdate("test", payload)
+			dbMutex.Unlock()
+		}
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("broadcasts blocked on a client that is not reading")
+	}
+
+	connMutex.RLock()
+	defer connMutex.RUnlock()
+	if len(connections) != 0 {
+		t.Errorf("stalled client still connected")
+	}
+}
+
+// TestAdminThemeListDoesNotWriteTheme reads a finished theme as admins and
+// players at once. Run it with -race: the list is built under a read lock,
+// so it must not write to the shared theme.
+func TestAdminThemeListDoesNotWriteTheme(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "d-admin"}
+	player := &User{ID: "u1"}
+	db.Users = append(db.Users, admin, player)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+
+	theme := newTestTheme("t1", 3, 9)
+	theme.Seed = 42
+	theme.IsComplete = true
+	theme.setState(ThemeFinished)
+	db.Themes = append(db.Themes, theme)
+
+	server := newTestServer(t)
+	adminToken, playerToken := testToken(t, admin.ID), testToken(t, player.ID)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if code, body := doRequest(t, http.MethodGet, server.URL+"/api/themes", adminToken, ""); code != http.StatusOK || !strings.Contains(body, `"seed":"42"`) {
+				t.Errorf("admin theme list = %d %s, want the seed", code, body)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if code, _ := doRequest(t, http.MethodGet, server.URL+"/api/themes", playerToken, ""); code != http.StatusOK {
+				t.Errorf("player theme list = %d", code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if forPlayers(theme) == theme {
+		t.Error("forPlayers returned the shared theme")
+	}
+}
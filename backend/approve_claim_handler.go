@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// approveClaimHandler accepts a pending claim. The card is checked again so
+// a claim cannot be approved after the winning items were unmarked, and the
+// claim is linked to the wins it confirms.
+func approveClaimHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID string `param:"id"`
+		ClaimID string `param:"claimId"`
+		Reason  string `json:"reason"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	claim, found := theme.getClaim(req.ClaimID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Claim not found"})
+	}
+
+	if claim.Status != ClaimPending {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Claim has already been resolved"})
+	}
+
+	card, found := theme.Cards[claim.CardID]
+	if !found || !card.active() {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Card no longer exists"})
+	}
+
+	card.checkBingo(theme)
+	if !card.IsWinner {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Card does not satisfy any win pattern"})
+	}
+
+	before := eventState(claim)
+	theme.resolve(claim, user, ClaimApproved, req.Reason, false)
+	claim.Patterns, claim.Valid = card.Patterns, true
+	theme.confirmWins(claim)
+
+	if err := store.SaveTheme(theme); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventClaimResolved, user, theme.ID, claim.ID, before, claim)
+
+	broadcastThemeUpdate(theme.ID, "claim_approved", claim)
+
+	return c.JSON(http.StatusOK, claim)
+}
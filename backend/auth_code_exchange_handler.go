@@ -52,6 +52,9 @@ func authCodeExchangeHandler(c echo.Context) error {
 	}
 
 	// Find or create user
+	dbMutex.Lock()
+	defer dbMutex.Unlock()
+
 	var user *User
 	for i := range db.Users {
 		if db.Users[i].DiscordID == discordUser.ID {
@@ -70,9 +73,10 @@ func authCodeExchangeHandler(c echo.Context) error {
 		}
 		db.Users = append(db.Users, newUser)
 		user = db.Users[len(db.Users)-1]
-		if err := saveDatabase(); err != nil {
+		if err := store.SaveUser(user); err != nil {
 			c.Logger().Error("Failed to save database:", err)
 		}
+		recordEvent(EventUserCreated, user, "", user.ID, nil, user)
 	}
 
 	// Generate JWT token
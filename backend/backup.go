@@ -0,0 +1,313 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const backupTimeFormat = "20060102-150405"
+
+// Backup kinds, used as the file name prefix
+const (
+	backupManual     = "manual"
+	backupScheduled  = "scheduled"
+	backupPreRestore = "pre-restore"
+)
+
+var backupNamePattern = regexp.MustCompile(`^(manual|scheduled|pre-restore)-(\d{8}-\d{6})(-\d+)?\.json$`)
+
+type BackupInfo struct {
+	Name      string    `json:"name"`
+	Kind      string    `json:"kind"`
+	Size      int64     `json:"size"`
+	CreatedAt time.Time `json:"created_at"`
+
+	// seq orders snapshots taken within the same second
+	seq int
+}
+
+func backupDir() string {
+	return cmp.Or(os.Getenv("BACKUP_DIR"), "data/backups")
+}
+
+// createBackup writes a snapshot of db to the backup directory. The caller
+// must hold dbMutex.
+func createBackup(kind string) (*BackupInfo, error) {
+	data, err := json.MarshalIndent(db, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	dir := backupDir()
+	now := time.Now().UTC()
+	base := fmt.Sprintf("%s-%s", kind, now.Format(backupTimeFormat))
+
+	// Several snapshots of the same kind within a second get a counter
+	name := base + ".json"
+	for i := 2; ; i++ {
+		if _, err := os.Stat(filepath.Join(dir, name)); errors.Is(err, fs.ErrNotExist) {
+			break
+		}
+		name = fmt.Sprintf("%s-%d.json", base, i)
+	}
+
+	if err := writeFileAtomic(filepath.Join(dir, name), data, nil); err != nil {
+		return nil, err
+	}
+
+	return &BackupInfo{
+		Name:      name,
+		Kind:      kind,
+		Size:      int64(len(data)),
+		CreatedAt: now,
+	}, nil
+}
+
+// listBackups returns every snapshot in the backup directory, newest first.
+func listBackups() ([]*BackupInfo, error) {
+	entries, err := os.ReadDir(backupDir())
+	if errors.Is(err, fs.ErrNotExist) {
+		return []*BackupInfo{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	backups := []*BackupInfo{}
+	for _, entry := range entries {
+		match := backupNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil || entry.IsDir() {
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return nil, err
+		}
+
+		createdAt, err := time.Parse(backupTimeFormat, match[2])
+		if err != nil {
+			continue
+		}
+
+		seq, _ := strconv.Atoi(strings.TrimPrefix(match[3], "-"))
+
+		backups = append(backups, &BackupInfo{
+			Name:      entry.Name(),
+			Kind:      match[1],
+			Size:      info.Size(),
+			CreatedAt: createdAt,
+			seq:       seq,
+		})
+	}
+
+	slices.SortFunc(backups, func(a, b *BackupInfo) int {
+		return cmp.Or(b.CreatedAt.Compare(a.CreatedAt), cmp.Compare(b.seq, a.seq), strings.Compare(b.Name, a.Name))
+	})
+
+	return backups, nil
+}
+
+// backupPath resolves a snapshot name to its file, rejecting anything that
+// is not a backup file name.
+func backupPath(name string) (string, bool) {
+	if !backupNamePattern.MatchString(name) {
+		return "", false
+	}
+	return filepath.Join(backupDir(), name), true
+}
+
+// pruneBackups deletes the oldest snapshots of the given kind so at most
+// keep remain.
+func pruneBackups(kind string, keep int) error {
+	backups, err := listBackups()
+	if err != nil {
+		return err
+	}
+
+	kept := 0
+	for _, backup := range backups {
+		if backup.Kind != kind {
+			continue
+		}
+		kept++
+		if kept <= keep {
+			continue
+		}
+		if err := os.Remove(filepath.Join(backupDir(), backup.Name)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// runBackupSchedule takes a scheduled snapshot every interval and keeps the
+// newest retention of them, until ctx is cancelled.
+func runBackupSchedule(ctx context.Context, interval time.Duration, retention int) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			dbMutex.RLock()
+			backup, err := createBackup(backupScheduled)
+			dbMutex.RUnlock()
+			if err != nil {
+				log.Println("Error creating scheduled backup:", err)
+				continue
+			}
+			log.Printf("Created scheduled backup %s", backup.Name)
+
+			if err := pruneBackups(backupScheduled, retention); err != nil {
+				log.Println("Error pruning backups:", err)
+			}
+		}
+	}
+}
+
+// parseBackup decodes and validates a snapshot, upgrading it to the current
+// schema version.
+func parseBackup(data []byte) (*Database, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	restored := newDatabase()
+	if err := decoder.Decode(restored); err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+
+	if _, err := migrateDatabase(restored); err != nil {
+		return nil, err
+	}
+
+	if err := validateDatabase(restored); err != nil {
+		return nil, fmt.Errorf("invalid backup: %w", err)
+	}
+
+	return restored, nil
+}
+
+// validateDatabase checks that IDs are present and unique and that every
+// card and the active theme point at something that exists.
+func validateDatabase(d *Database) error {
+	userIDs := make(map[string]bool)
+	for _, user := range d.Users {
+		if user == nil || user.ID == "" {
+			return errors.New("user without an id")
+		}
+		if userIDs[user.ID] {
+			return fmt.Errorf("duplicate user id %s", user.ID)
+		}
+		userIDs[user.ID] = true
+	}
+
+	themeIDs := make(map[string]bool)
+	for _, theme := range d.Themes {
+		if theme == nil || theme.ID == "" {
+			return errors.New("theme without an id")
+		}
+		if themeIDs[theme.ID] {
+			return fmt.Errorf("duplicate theme id %s", theme.ID)
+		}
+		themeIDs[theme.ID] = true
+
+		if _, ok := themeTransitions[theme.State]; !ok {
+			return fmt.Errorf("theme %s has unknown state %q", theme.ID, theme.State)
+		}
+
+		itemIDs := make(map[string]bool)
+		for _, item := range theme.Items {
+			if item == nil || item.ID == "" {
+				return fmt.Errorf("theme %s has an item without an id", theme.ID)
+			}
+			if itemIDs[item.ID] {
+				return fmt.Errorf("theme %s has duplicate item id %s", theme.ID, item.ID)
+			}
+			itemIDs[item.ID] = true
+		}
+
+		if theme.Cards == nil {
+			theme.Cards = make(map[string]*Card)
+		}
+		for cardID, card := range theme.Cards {
+			if card == nil || card.ID == "" {
+				return fmt.Errorf("theme %s has a card without an id", theme.ID)
+			}
+			if card.ThemeID != theme.ID || card.ID != cardID {
+				return fmt.Errorf("card %s is filed under the wrong theme or id", card.ID)
+			}
+			if _, ok := theme.getTeam(card.TeamID); card.TeamID != "" && !ok {
+				return fmt.Errorf("card %s belongs to unknown team %s", card.ID, card.TeamID)
+			}
+		}
+	}
+
+	if d.ActiveThemeID != "" && !themeIDs[d.ActiveThemeID] {
+		return fmt.Errorf("active theme %s does not exist", d.ActiveThemeID)
+	}
+
+	// Each theme may be active in one room at most
+	roomIDs := make(map[string]bool)
+	activeIn := map[string]string{d.ActiveThemeID: defaultRoomID}
+	for _, room := range d.Rooms {
+		if room == nil || room.ID == "" || room.ID == defaultRoomID {
+			return errors.New("room without a valid id")
+		}
+		if roomIDs[room.ID] {
+			return fmt.Errorf("duplicate room id %s", room.ID)
+		}
+		roomIDs[room.ID] = true
+
+		if room.ActiveThemeID == "" {
+			continue
+		}
+		if !themeIDs[room.ActiveThemeID] {
+			return fmt.Errorf("room %s has unknown active theme %s", room.ID, room.ActiveThemeID)
+		}
+		if other, ok := activeIn[room.ActiveThemeID]; ok {
+			return fmt.Errorf("theme %s is active in rooms %s and %s", room.ActiveThemeID, other, room.ID)
+		}
+		activeIn[room.ActiveThemeID] = room.ID
+	}
+
+	seasonIDs := make(map[string]bool)
+	for _, season := range d.Seasons {
+		if season == nil || season.ID == "" {
+			return errors.New("season without an id")
+		}
+		if seasonIDs[season.ID] {
+			return fmt.Errorf("duplicate season id %s", season.ID)
+		}
+		seasonIDs[season.ID] = true
+	}
+
+	templateIDs := make(map[string]bool)
+	for _, template := range d.Templates {
+		if template == nil || template.ID == "" {
+			return errors.New("template without an id")
+		}
+		if templateIDs[template.ID] {
+			return fmt.Errorf("duplicate template id %s", template.ID)
+		}
+		templateIDs[template.ID] = true
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"testing"
+)
+
+func TestBackupListPruneAndParse(t *testing.T) {
+	t.Setenv("BACKUP_DIR", t.TempDir())
+	useTestStore(t)
+
+	db.Themes = append(db.Themes, newTestTheme("t1", 3, 9))
+	db.ActiveThemeID = "t1"
+
+	for range 3 {
+		if _, err := createBackup(backupScheduled); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := createBackup(backupManual); err != nil {
+		t.Fatal(err)
+	}
+
+	backups, err := listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 4 {
+		t.Fatalf("got %d backups, want 4", len(backups))
+	}
+
+	if err := pruneBackups(backupScheduled, 1); err != nil {
+		t.Fatal(err)
+	}
+	backups, err = listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 2 {
+		t.Fatalf("got %d backups after pruning, want 1 scheduled and 1 manual", len(backups))
+	}
+
+	path, ok := backupPath(backups[0].Name)
+	if !ok {
+		t.Fatalf("backup name %q rejected", backups[0].Name)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored, err := parseBackup(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ActiveThemeID != "t1" || restored.SchemaVersion != currentSchemaVersion {
+		t.Errorf("backup restored active theme %q at version %d", restored.ActiveThemeID, restored.SchemaVersion)
+	}
+}
+
+func TestParseBackupRejectsInvalid(t *testing.T) {
+	for name, data := range map[string]string{
+		"unknown field":        `{"bogus": 1}`,
+		"missing active theme": `{"active_theme_id": "gone"}`,
+		"duplicate user":       `{"users": [{"id": "u1"}, {"id": "u1"}]}`,
+		"not json":             `{`,
+	} {
+		if _, err := parseBackup([]byte(data)); err == nil {
+			t.Errorf("%s: backup accepted", name)
+		}
+	}
+
+	for _, name := range []string{"../database.json", "manual-20240101-000000.json.tmp", "notes.txt"} {
+		if _, ok := backupPath(name); ok {
+			t.Errorf("backup name %q accepted", name)
+		}
+	}
+}
+
+func TestRestoreBackupHandler(t *testing.T) {
+	t.Setenv("BACKUP_DIR", t.TempDir())
+	t.Setenv("ADMIN_DISCORD_IDS", "")
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "d-admin"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	server := newTestServer(t)
+
+	snapshot := newDatabase()
+	snapshot.Users = []*User{admin, {ID: "u2"}}
+	snapshot.AdminDiscordIDs = []string{admin.DiscordID}
+	snapshot.Themes = []*Theme{newTestTheme("restored", 3, 9)}
+	snapshot.ActiveThemeID = "restored"
+	body, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, resp := doRequest(t, http.MethodPost, server.URL+"/api/admin/backups/restore", testToken(t, admin.ID), string(body))
+	if code != http.StatusOK {
+		t.Fatalf("restore = %d %s", code, resp)
+	}
+
+	dbMutex.RLock()
+	defer dbMutex.RUnlock()
+	if db.ActiveThemeID != "restored" || len(db.Users) != 2 {
+		t.Errorf("database not replaced by the backup")
+	}
+
+	backups, err := listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].Kind != backupPreRestore {
+		t.Errorf("want one pre-restore backup, got %+v", backups)
+	}
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand/v2"
+	"slices"
+	"time"
+)
+
+// Caller statuses
+const (
+	CallerRunning  = "running"
+	CallerPaused   = "paused"
+	CallerFinished = "finished"
+)
+
+const (
+	defaultCallerInterval = 30
+	maxCallerInterval     = 3600
+)
+
+// Caller lets the server draw a theme's items on a timer. The draw order
+// is fixed when the caller starts and persisted with the theme, so a
+// restarted server carries on where it left off.
+type Caller struct {
+	Status          string     `json:"status"`
+	IntervalSeconds int        `json:"interval_seconds"`
+	DrawOrder       []string   `json:"draw_order,omitempty"`
+	Drawn           int        `json:"drawn"`
+	NextDrawAt      *time.Time `json:"next_draw_at,omitempty"`
+	StartedAt       time.Time  `json:"started_at"`
+}
+
+// forPlayers returns t as players may see it. The caller's upcoming draws
+// are hidden, and so is the seed until the theme is complete, since it
+// would let anyone work out every card. It always returns a shallow copy,
+// so callers may change its fields without touching the shared theme.
+func forPlayers(t *Theme) *Theme {
+	c := *t
+	if t.Caller != nil {
+		caller := *t.Caller
+		caller.DrawOrder = nil
+		c.Caller = &caller
+	}
+	if !t.IsComplete {
+		c.Seed = 0
+	}
+	return &c
+}
+
+// startCaller shuffles the theme's unmarked items into a new draw order
+// and schedules the first draw.
+func (t *Theme) startCaller(interval int, now time.Time) {
+	var order []string
+	for _, item := range t.Items {
+		if !item.Marked {
+			order = append(order, item.ID)
+		}
+	}
+	rand.Shuffle(len(order), func(i, j int) {
+		order[i], order[j] = order[j], order[i]
+	})
+
+	t.Caller = &Caller{
+		Status:          CallerRunning,
+		IntervalSeconds: interval,
+		DrawOrder:       order,
+		StartedAt:       now,
+	}
+	t.Caller.schedule(now)
+}
+
+func (c *Caller) schedule(now time.Time) {
+	next := now.Add(time.Duration(c.IntervalSeconds) * time.Second)
+	c.NextDrawAt = &next
+}
+
+// due reports whether the caller should draw at now.
+func (c *Caller) due(now time.Time) bool {
+	return c.Status == CallerRunning && c.NextDrawAt != nil && !now.Before(*c.NextDrawAt)
+}
+
+// remaining returns how many items are left to draw.
+func (c *Caller) remaining() int {
+	return len(c.DrawOrder) - c.Drawn
+}
+
+// drawNext marks the next item in the draw order. Items that were deleted
+// or marked by hand since the caller started are passed over. It returns
+// nil once every item has been drawn.
+func (t *Theme) drawNext(now time.Time) *Item {
+	caller := t.Caller
+	for caller.Drawn < len(caller.DrawOrder) {
+		itemID := caller.DrawOrder[caller.Drawn]
+		caller.Drawn++
+
+		item, ok := t.GetItem(itemID)
+		if !ok || item.Marked {
+			continue
+		}
+
+		item.Marked = true
+		caller.schedule(now)
+		return item
+	}
+
+	caller.Status = CallerFinished
+	caller.NextDrawAt = nil
+	return nil
+}
+
+// replayDraw moves the draw position past itemID, as drawNext did when the
+// item was drawn.
+func (c *Caller) replayDraw(itemID string, at time.Time) {
+	if i := slices.Index(c.DrawOrder, itemID); i >= c.Drawn {
+		c.Drawn = i + 1
+		c.schedule(at)
+	}
+}
+
+// callDraw draws the theme's next item, then saves and broadcasts the
+// result. Callers hold dbMutex.
+func callDraw(t *Theme, actor *User) *Item {
+	themeBefore := eventState(t)
+
+	item := t.drawNext(time.Now())
+
+	if err := store.SaveTheme(t); err != nil {
+		log.Println("Error saving database:", err)
+	}
+
+	if item == nil {
+		recordEvent(EventThemeUpdated, actor, t.ID, t.ID, themeBefore, t)
+		broadcastThemeUpdate(t.ID, "caller_finished", map[string]any{
+			"theme_id": t.ID,
+		})
+		return nil
+	}
+
+	before := *item
+	before.Marked = false
+
+	if err := store.SaveItem(t.ID, item); err != nil {
+		log.Println("Error saving database:", err)
+	}
+	recordEvent(EventItemDrawn, actor, t.ID, item.ID, &before, item)
+
+	broadcastThemeUpdate(t.ID, "item_drawn", map[string]any{
+		"theme_id":  t.ID,
+		"item":      item,
+		"drawn":     t.Caller.Drawn,
+		"remaining": t.Caller.remaining(),
+	})
+
+	publishWins(t, actor, item.ID)
+
+	return item
+}
+
+// runCaller draws items for every running caller as they fall due, until
+// ctx is cancelled.
+func runCaller(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			dbMutex.Lock()
+			for _, theme := range db.Themes {
+				if theme.Caller != nil && !theme.IsComplete && theme.Caller.due(now) {
+					callDraw(theme, nil)
+				}
+			}
+			dbMutex.Unlock()
+		}
+	}
+}
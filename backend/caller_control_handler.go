@@ -0,0 +1,105 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// callerControlHandler starts, pauses, resumes or skips ahead the automated
+// caller of a theme. Starting takes an open or locked theme live; skip
+// draws the next item immediately.
+func callerControlHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID         string `param:"id"`
+		Action          string `param:"action"`
+		IntervalSeconds int    `json:"interval_seconds"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	if theme.IsComplete {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Theme is complete"})
+	}
+
+	status := ""
+	if theme.Caller != nil {
+		status = theme.Caller.Status
+	}
+
+	before := eventState(theme)
+	now := time.Now()
+
+	switch req.Action {
+	case "start":
+		if status == CallerRunning || status == CallerPaused {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Caller is already started"})
+		}
+		interval := cmp.Or(req.IntervalSeconds, defaultCallerInterval)
+		if interval < 1 || interval > maxCallerInterval {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Interval must be between 1 and %d seconds", maxCallerInterval)})
+		}
+		theme.startCaller(interval, now)
+		if theme.Caller.remaining() == 0 {
+			theme.Caller = nil
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Theme has no items left to draw"})
+		}
+		if err := startPlay(theme, user); err != nil {
+			theme.Caller = nil
+			return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+		}
+
+	case "pause":
+		if status != CallerRunning {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Caller is not running"})
+		}
+		theme.Caller.Status = CallerPaused
+		theme.Caller.NextDrawAt = nil
+
+	case "resume":
+		if status != CallerPaused {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Caller is not paused"})
+		}
+		theme.Caller.Status = CallerRunning
+		theme.Caller.schedule(now)
+
+	case "skip":
+		if status != CallerRunning && status != CallerPaused {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Caller is not started"})
+		}
+		item := callDraw(theme, user)
+		return c.JSON(http.StatusOK, map[string]any{
+			"caller": theme.Caller,
+			"item":   item,
+		})
+
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown caller action"})
+	}
+
+	if err := store.SaveTheme(theme); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventThemeUpdated, user, theme.ID, theme.ID, before, theme)
+
+	broadcastThemeUpdate(theme.ID, "caller_updated", map[string]any{
+		"theme_id": theme.ID,
+		"caller":   forPlayers(theme).Caller,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"caller": theme.Caller,
+	})
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestCallerDrawsEveryUnmarkedItem(t *testing.T) {
+	theme := newTestTheme("t1", 3, 9)
+	markItems(t, theme, "a")
+
+	now := time.Unix(100, 0)
+	theme.startCaller(5, now)
+	if got := theme.Caller.remaining(); got != 8 {
+		t.Fatalf("remaining = %d, want 8", got)
+	}
+	if theme.Caller.due(now) {
+		t.Error("caller is due before its interval has passed")
+	}
+	if !theme.Caller.due(now.Add(5 * time.Second)) {
+		t.Error("caller is not due once its interval has passed")
+	}
+
+	// An item marked by hand is passed over when its turn comes
+	markItems(t, theme, "d")
+
+	drawn := 0
+	for theme.drawNext(now) != nil {
+		drawn++
+	}
+	if drawn != 7 {
+		t.Errorf("drew %d items, want 7", drawn)
+	}
+	if theme.Caller.Status != CallerFinished || theme.Caller.NextDrawAt != nil {
+		t.Errorf("caller = %+v, want finished with nothing scheduled", theme.Caller)
+	}
+	for _, item := range theme.Items {
+		if !item.Marked {
+			t.Errorf("item %s was never drawn", item.ID)
+		}
+	}
+}
+
+func TestForPlayersHidesDrawOrder(t *testing.T) {
+	theme := newTestTheme("t1", 3, 9)
+	theme.startCaller(5, time.Now())
+
+	hidden := forPlayers(theme)
+	if hidden.Caller.DrawOrder != nil {
+		t.Error("draw order sent to players")
+	}
+	if len(theme.Caller.DrawOrder) != 9 {
+		t.Error("hiding the draw order changed the theme")
+	}
+}
+
+func TestReplayDraw(t *testing.T) {
+	caller := &Caller{DrawOrder: []string{"x", "y", "z"}, IntervalSeconds: 1}
+
+	caller.replayDraw("y", time.Now())
+	if caller.Drawn != 2 {
+		t.Errorf("drawn = %d after replaying y, want 2", caller.Drawn)
+	}
+
+	// Replaying an earlier draw does not move the position back
+	caller.replayDraw("x", time.Now())
+	if caller.Drawn != 2 {
+		t.Errorf("drawn = %d after replaying x, want 2", caller.Drawn)
+	}
+}
+
+func TestCallerControlHandler(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "admin-discord"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	theme := newTestTheme("t1", 3, 9)
+	theme.setState(ThemeLive)
+	db.Themes = append(db.Themes, theme)
+
+	server := newTestServer(t)
+	token := testToken(t, admin.ID)
+	control := func(action, body string) (int, string) {
+		return doRequest(t, http.MethodPost, server.URL+"/api/admin/themes/t1/caller/"+action, token, body)
+	}
+
+	if code, _ := control("pause", ""); code != http.StatusConflict {
+		t.Errorf("pausing a caller that never started = %d, want %d", code, http.StatusConflict)
+	}
+	if code, _ := control("start", `{"interval_seconds": 0}`); code != http.StatusOK {
+		t.Fatalf("start = %d", code)
+	}
+	if theme.Caller.IntervalSeconds != defaultCallerInterval {
+		t.Errorf("interval = %d, want the default %d", theme.Caller.IntervalSeconds, defaultCallerInterval)
+	}
+	if code, _ := control("start", ""); code != http.StatusConflict {
+		t.Errorf("starting twice = %d, want %d", code, http.StatusConflict)
+	}
+
+	if code, _ := control("pause", ""); code != http.StatusOK || theme.Caller.Status != CallerPaused {
+		t.Errorf("pause = %d, status %s", code, theme.Caller.Status)
+	}
+	if code, _ := control("resume", ""); code != http.StatusOK || theme.Caller.Status != CallerRunning {
+		t.Errorf("resume = %d, status %s", code, theme.Caller.Status)
+	}
+
+	code, body := control("skip", "")
+	if code != http.StatusOK {
+		t.Fatalf("skip = %d %s", code, body)
+	}
+	var resp struct {
+		Item *Item `json:"item"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Item == nil || !resp.Item.Marked || theme.Caller.Drawn != 1 {
+		t.Errorf("skip drew %+v, caller at %d", resp.Item, theme.Caller.Drawn)
+	}
+
+	if code, _ := control("shuffle", ""); code != http.StatusBadRequest {
+		t.Errorf("unknown action = %d, want %d", code, http.StatusBadRequest)
+	}
+}
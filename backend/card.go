@@ -1,6 +1,14 @@
 package main
 
-import "time"
+import (
+	"cmp"
+	"math/bits"
+	"slices"
+	"time"
+)
+
+// freeSpaceID marks the free square of a card, which is always marked
+const freeSpaceID = "FREE_SPACE"
 
 type Card struct {
 	ID        string     `json:"id"`
@@ -9,64 +17,109 @@ type Card struct {
 	Items     [][]string `json:"items"`
 	CreatedAt time.Time  `json:"created_at"`
 	IsWinner  bool       `json:"is_winner"`
+	// Patterns lists the names of the theme's win patterns the card satisfies
+	Patterns []string `json:"patterns,omitempty"`
+	// Remaining is how many squares are left on the card's closest pattern
+	// and NeededItems the items on those squares, across every pattern
+	// equally close
+	Remaining   int      `json:"remaining"`
+	NeededItems []string `json:"needed_items,omitempty"`
+	// Index and PoolDigest identify how the card was generated, so it can
+	// be regenerated for verification
+	Index      int    `json:"index"`
+	Attempt    int    `json:"attempt,omitempty"`
+	PoolDigest string `json:"pool_digest,omitempty"`
+	// RetiredAt is set when the card is rerolled; it is kept for
+	// verification but no longer plays
+	RetiredAt *time.Time `json:"retired_at,omitempty"`
+	// Daubed lists the items the player has marked in daub mode
+	Daubed []string `json:"daubed,omitempty"`
+	// TeamID is set on cards shared by a team; UserID is then the member
+	// the card was dealt to
+	TeamID string `json:"team_id,omitempty"`
 }
 
 func (c *Card) checkBingo(theme *Theme) {
-	const gridSize = 5
-
-	// Check rows
-	if c.hasWinningRow(gridSize, theme) {
-		c.IsWinner = true
-		return
+	// Cards keep the size they were generated with
+	gridSize := len(c.Items)
+	marked := c.markedCells(gridSize, theme)
+
+	c.Patterns = nil
+	for _, pattern := range theme.winPatterns() {
+		if pattern.matches(gridSize, marked) {
+			c.Patterns = append(c.Patterns, pattern.displayName())
+		}
 	}
 
-	// Check columns
-	if c.hasWinningColumn(gridSize, theme) {
-		c.IsWinner = true
-		return
-	}
+	c.IsWinner = len(c.Patterns) > 0
+	c.updateNearWin(gridSize, marked, theme)
+}
 
-	// Check diagonals
-	if c.hasWinningDiagonal(gridSize, theme) {
-		c.IsWinner = true
-		return
+// updateNearWin finds the shapes of the theme's patterns with the fewest
+// unmarked cells and records how many cells that is and which items sit
+// on them.
+func (c *Card) updateNearWin(gridSize int, marked uint64, theme *Theme) {
+	best := -1
+	var missing uint64
+	for _, pattern := range theme.winPatterns() {
+		for _, shape := range pattern.shapes(gridSize) {
+			left := shape &^ marked
+			switch n := bits.OnesCount64(left); {
+			case best < 0 || n < best:
+				best, missing = n, left
+			case n == best:
+				missing |= left
+			}
+		}
 	}
 
-	c.IsWinner = false
-}
-
-// hasWinningRow checks if any row has all items marked
-func (c *Card) hasWinningRow(gridSize int, theme *Theme) bool {
+	c.Remaining = max(best, 0)
+	c.NeededItems = nil
 	for row := range gridSize {
-		if c.isRowComplete(row, gridSize, theme) {
-			return true
+		for col := range gridSize {
+			if missing&cellBit(gridSize, row, col) != 0 {
+				c.NeededItems = append(c.NeededItems, c.Items[row][col])
+			}
 		}
 	}
-	return false
 }
 
-// hasWinningColumn checks if any column has all items marked
-func (c *Card) hasWinningColumn(gridSize int, theme *Theme) bool {
-	for col := range gridSize {
-		if c.isColumnComplete(col, gridSize, theme) {
-			return true
-		}
-	}
-	return false
+// holderID returns the team sharing the card, or the player holding it.
+func (c *Card) holderID() string {
+	return cmp.Or(c.TeamID, c.UserID)
 }
 
-// hasWinningDiagonal checks if either diagonal has all items marked
-func (c *Card) hasWinningDiagonal(gridSize int, theme *Theme) bool {
-	return c.isMainDiagonalComplete(gridSize, theme) || c.isAntiDiagonalComplete(gridSize, theme)
+// active reports whether the card is still in play.
+func (c *Card) active() bool {
+	return c.RetiredAt == nil
+}
+
+// markedCells returns a bitmask with bit row*gridSize+col set for every
+// marked cell
+func (c *Card) markedCells(gridSize int, theme *Theme) uint64 {
+	var marked uint64
+	for row := range gridSize {
+		for col := range gridSize {
+			if c.isItemMarked(c.Items[row][col], theme) {
+				marked |= cellBit(gridSize, row, col)
+			}
+		}
+	}
+	return marked
 }
 
-// isItemMarked checks if an item with the given ID is marked in the theme
+// isItemMarked checks if an item with the given ID is marked in the theme.
+// In daub mode the player must also have daubed it.
 func (c *Card) isItemMarked(itemID string, theme *Theme) bool {
 	// Handle FREE_SPACE specially - it's always considered marked
-	if itemID == "FREE_SPACE" {
+	if itemID == freeSpaceID {
 		return true
 	}
 
+	if theme.daubing() && !slices.Contains(c.Daubed, itemID) {
+		return false
+	}
+
 	for _, item := range theme.Items {
 		if item.ID == itemID {
 			return item.Marked
@@ -75,42 +128,12 @@ func (c *Card) isItemMarked(itemID string, theme *Theme) bool {
 	return false
 }
 
-// isRowComplete checks if a specific row has all items marked
-func (c *Card) isRowComplete(row, gridSize int, theme *Theme) bool {
-	for col := range gridSize {
-		if !c.isItemMarked(c.Items[row][col], theme) {
-			return false
-		}
-	}
-	return true
-}
-
-// isColumnComplete checks if a specific column has all items marked
-func (c *Card) isColumnComplete(col, gridSize int, theme *Theme) bool {
-	for row := range gridSize {
-		if !c.isItemMarked(c.Items[row][col], theme) {
-			return false
-		}
-	}
-	return true
-}
-
-// isMainDiagonalComplete checks if the main diagonal (top-left to bottom-right) has all items marked
-func (c *Card) isMainDiagonalComplete(gridSize int, theme *Theme) bool {
-	for i := range gridSize {
-		if !c.isItemMarked(c.Items[i][i], theme) {
-			return false
-		}
-	}
-	return true
-}
-
-// isAntiDiagonalComplete checks if the anti-diagonal (top-right to bottom-left) has all items marked
-func (c *Card) isAntiDiagonalComplete(gridSize int, theme *Theme) bool {
-	for i := range gridSize {
-		if !c.isItemMarked(c.Items[i][gridSize-1-i], theme) {
-			return false
+// hasItem reports whether the item appears on the card.
+func (c *Card) hasItem(itemID string) bool {
+	for _, row := range c.Items {
+		if slices.Contains(row, itemID) {
+			return true
 		}
 	}
-	return true
+	return false
 }
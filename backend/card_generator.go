@@ -0,0 +1,265 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Cards are generated deterministically from the theme's seed, the user
+// and the card's index, so any card can be regenerated later to prove it
+// was dealt fairly. The shuffle is implemented here rather than with
+// rand.Shuffle so the result only depends on the ChaCha8 stream, which is
+// stable across Go releases.
+
+// Seed is a theme's card generation seed. It is sent as a string because
+// JavaScript numbers cannot hold every uint64; numbers are still accepted
+// when reading databases written before that.
+type Seed uint64
+
+func (s Seed) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strconv.FormatUint(uint64(s), 10))
+}
+
+func (s *Seed) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	text := strings.Trim(string(data), `"`)
+	n, err := strconv.ParseUint(text, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid seed %s", data)
+	}
+	*s = Seed(n)
+	return nil
+}
+
+// newThemeSeed returns a fresh random seed for a theme.
+func newThemeSeed() Seed {
+	return Seed(rand.Uint64())
+}
+
+// maxDealAttempts bounds how many grids are tried when looking for one that
+// is different enough from the cards already dealt.
+const maxDealAttempts = 1000
+
+// cardSource returns the random stream for one attempt at one card.
+func cardSource(themeSeed Seed, userID string, index, attempt int) *rand.ChaCha8 {
+	h := sha256.New()
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(themeSeed)))
+	h.Write([]byte(userID))
+	h.Write([]byte{0})
+	h.Write(binary.BigEndian.AppendUint64(nil, uint64(index)))
+	// Keeps first attempts identical to cards dealt before retries existed
+	if attempt > 0 {
+		h.Write(binary.BigEndian.AppendUint64(nil, uint64(attempt)))
+	}
+
+	var seed [32]byte
+	copy(seed[:], h.Sum(nil))
+	return rand.NewChaCha8(seed)
+}
+
+// uniform returns a number in [0, n) without modulo bias.
+func uniform(src rand.Source, n uint64) uint64 {
+	limit := -n % n // 2^64 mod n
+	for {
+		v := src.Uint64()
+		if v >= limit {
+			return v % n
+		}
+	}
+}
+
+// shuffleIDs shuffles ids in place with a Fisher-Yates shuffle.
+func shuffleIDs(src rand.Source, ids []string) {
+	for i := len(ids) - 1; i > 0; i-- {
+		j := uniform(src, uint64(i+1))
+		ids[i], ids[j] = ids[j], ids[i]
+	}
+}
+
+// itemPool returns the theme's item IDs in a stable order, along with a
+// digest that changes whenever anything that affects card generation does.
+func (t *Theme) itemPool() (ids []string, digest string) {
+	ids = make([]string, len(t.Items))
+	for i, item := range t.Items {
+		ids[i] = item.ID
+	}
+	slices.Sort(ids)
+
+	// Weights, categories and rules only join the digest when they are
+	// used, so digests of cards dealt before they existed still match
+	lines := slices.Clone(ids)
+	if t.usesLayout() {
+		items := make(map[string]*Item, len(t.Items))
+		for _, item := range t.Items {
+			items[item.ID] = item
+		}
+		for i, id := range lines {
+			lines[i] = fmt.Sprintf("%s\t%g\t%s", id, items[id].weight(), items[id].Category)
+		}
+		for _, rule := range t.LayoutRules {
+			lines = append(lines, fmt.Sprintf("%s\t%s\t%d\t%d", rule.Type, rule.Category, rule.Index, rule.Max))
+		}
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return ids, hex.EncodeToString(sum[:])
+}
+
+// generateGrid deals a size x size grid for one attempt at the user's
+// index-th card. It reports false when the theme's layout rules could not
+// be satisfied on this attempt.
+func (t *Theme) generateGrid(userID string, index, attempt, size int) ([][]string, bool) {
+	src := cardSource(t.Seed, userID, index, attempt)
+	if t.usesLayout() {
+		return t.layoutGrid(src, size)
+	}
+
+	pool, _ := t.itemPool()
+	shuffleIDs(src, pool)
+	selected := pool[:requiredItems(size)]
+
+	// Fill the grid, leaving the free space
+	grid := make([][]string, size)
+	freeRow, freeCol, hasFree := freeSpace(size)
+	next := 0
+	for i := range grid {
+		grid[i] = make([]string, size)
+		for j := range grid[i] {
+			if hasFree && i == freeRow && j == freeCol {
+				grid[i][j] = freeSpaceID
+				continue
+			}
+			grid[i][j] = selected[next]
+			next++
+		}
+	}
+
+	return grid, true
+}
+
+// validateMinCardDistance checks that cards of the given size can differ
+// in distance cells.
+func validateMinCardDistance(distance, size int) error {
+	if cells := requiredItems(size); distance < 0 || distance > cells {
+		return fmt.Errorf("minimum card distance must be between 0 and %d", cells)
+	}
+	return nil
+}
+
+// minCardDistance returns how many cells any two cards must differ in.
+// Cards are never identical, even when no distance is configured.
+func (t *Theme) minCardDistance() int {
+	return max(t.MinCardDistance, 1)
+}
+
+// cardDistance counts the cells in which two grids differ. Grids of
+// different sizes never collide.
+func cardDistance(a, b [][]string) int {
+	if len(a) != len(b) {
+		return len(a) * len(a)
+	}
+	distance := 0
+	for i := range a {
+		for j := range a[i] {
+			if a[i][j] != b[i][j] {
+				distance++
+			}
+		}
+	}
+	return distance
+}
+
+// dealGrid generates the user's index-th card, retrying until it is at
+// least minCardDistance cells away from every other user's card. It fails
+// when the item pool is too small to find such a card.
+func (t *Theme) dealGrid(userID string, index, size int) (grid [][]string, attempt int, err error) {
+	minDistance := t.minCardDistance()
+	if cells := requiredItems(size); minDistance > cells {
+		return nil, 0, fmt.Errorf("minimum card distance %d is more than the %d cells on a card", minDistance, cells)
+	}
+
+	laidOut := false
+	for attempt := range maxDealAttempts {
+		grid, ok := t.generateGrid(userID, index, attempt, size)
+		if !ok {
+			continue
+		}
+		laidOut = true
+		if t.farFromOtherCards(grid, minDistance) {
+			return grid, attempt, nil
+		}
+	}
+
+	if !laidOut {
+		return nil, 0, fmt.Errorf("item pool is too small: the layout rules cannot be satisfied by the theme's items")
+	}
+	return nil, 0, fmt.Errorf("item pool is too small: could not deal a card that differs from every other card in at least %d cells", minDistance)
+}
+
+func (t *Theme) farFromOtherCards(grid [][]string, minDistance int) bool {
+	for _, card := range t.Cards {
+		if card.active() && cardDistance(card.Items, grid) < minDistance {
+			return false
+		}
+	}
+	return true
+}
+
+// CardVerification is the result of regenerating a stored card.
+type CardVerification struct {
+	CardID   string     `json:"card_id"`
+	UserID   string     `json:"user_id"`
+	TeamID   string     `json:"team_id,omitempty"`
+	Index    int        `json:"index"`
+	Attempt  int        `json:"attempt"`
+	Seed     Seed       `json:"seed"`
+	Matches  bool       `json:"matches"`
+	Reason   string     `json:"reason,omitempty"`
+	Stored   [][]string `json:"stored"`
+	Expected [][]string `json:"expected,omitempty"`
+}
+
+// verifyCard regenerates card from the theme's seed and compares it with
+// what was stored.
+func (t *Theme) verifyCard(card *Card) CardVerification {
+	v := CardVerification{
+		CardID:  card.ID,
+		UserID:  card.UserID,
+		TeamID:  card.TeamID,
+		Index:   card.Index,
+		Attempt: card.Attempt,
+		Seed:    t.Seed,
+		Stored:  card.Items,
+	}
+
+	if card.PoolDigest == "" {
+		v.Reason = "card was generated before cards were seeded"
+		return v
+	}
+	if _, digest := t.itemPool(); digest != card.PoolDigest {
+		v.Reason = "theme items have changed since the card was generated"
+		return v
+	}
+
+	expected, ok := t.generateGrid(card.holderID(), card.Index, card.Attempt, len(card.Items))
+	if !ok {
+		v.Reason = "the layout rules cannot be satisfied when regenerating the card"
+		return v
+	}
+	v.Expected = expected
+	v.Matches = slices.EqualFunc(v.Expected, card.Items, slices.Equal)
+	if !v.Matches {
+		v.Reason = "stored card differs from the regenerated card"
+	}
+
+	return v
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestGenerateGridIsReproducible(t *testing.T) {
+	theme := newTestTheme("t1", 5, 30)
+	theme.Seed = 42
+
+	first, ok := theme.generateGrid("u1", 0, 0, 5)
+	if !ok {
+		t.Fatal("no grid generated")
+	}
+	again, _ := theme.generateGrid("u1", 0, 0, 5)
+	if !slices.EqualFunc(first, again, slices.Equal) {
+		t.Errorf("same inputs gave different grids:\n%v\n%v", first, again)
+	}
+
+	second, _ := theme.generateGrid("u1", 1, 0, 5)
+	other, _ := theme.generateGrid("u2", 0, 0, 5)
+	if slices.EqualFunc(first, second, slices.Equal) || slices.EqualFunc(first, other, slices.Equal) {
+		t.Error("different cards were given the same grid")
+	}
+}
+
+func TestVerifyCard(t *testing.T) {
+	theme := newTestTheme("t1", 5, 30)
+	theme.Seed = 42
+
+	card, err := theme.NewCard(&User{ID: "u1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := theme.verifyCard(card); !v.Matches {
+		t.Fatalf("freshly dealt card does not verify: %s", v.Reason)
+	}
+
+	card.Items[0][0], card.Items[0][1] = card.Items[0][1], card.Items[0][0]
+	if v := theme.verifyCard(card); v.Matches {
+		t.Error("tampered card verifies")
+	}
+
+	theme.Items = theme.Items[1:]
+	if v := theme.verifyCard(card); v.Matches || v.Reason == "" {
+		t.Errorf("verification after the items changed = %+v, want a reason it cannot match", v)
+	}
+}
+
+func TestSeedJSON(t *testing.T) {
+	// Larger than JavaScript can hold exactly
+	seed := Seed(1<<63 + 1)
+
+	data, err := json.Marshal(seed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `"9223372036854775809"` {
+		t.Errorf("seed encoded as %s, want a string", data)
+	}
+
+	for _, input := range []string{`"9223372036854775809"`, `9223372036854775809`} {
+		var got Seed
+		if err := json.Unmarshal([]byte(input), &got); err != nil {
+			t.Errorf("decoding %s: %v", input, err)
+		} else if got != seed {
+			t.Errorf("decoding %s = %d, want %d", input, got, seed)
+		}
+	}
+
+	var got Seed
+	if err := json.Unmarshal([]byte(`"lucky"`), &got); err == nil {
+		t.Error("decoded a seed that is not a number")
+	}
+}
+
+func TestForPlayersHidesSeedUntilComplete(t *testing.T) {
+	theme := newTestTheme("t1", 3, 9)
+	theme.Seed = 42
+	theme.setState(ThemeLive)
+
+	if got := forPlayers(theme).Seed; got != 0 {
+		t.Errorf("seed %d sent to players during the game", got)
+	}
+	if theme.Seed != 42 {
+		t.Error("hiding the seed changed the theme")
+	}
+
+	theme.setState(ThemeFinished)
+	if got := forPlayers(theme).Seed; got != 42 {
+		t.Errorf("seed = %d once finished, want 42", got)
+	}
+}
+
+func TestGetThemesHidesSeedFromPlayers(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "admin-discord"}
+	player := &User{ID: "u1"}
+	db.Users = append(db.Users, admin, player)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	theme := newTestTheme("t1", 3, 9)
+	theme.Seed = 42
+	db.Themes = append(db.Themes, theme)
+
+	server := newTestServer(t)
+
+	_, body := doRequest(t, http.MethodGet, server.URL+"/api/themes", testToken(t, player.ID), "")
+	if strings.Contains(body, `"seed"`) {
+		t.Errorf("players were sent the seed: %s", body)
+	}
+
+	_, body = doRequest(t, http.MethodGet, server.URL+"/api/themes", testToken(t, admin.ID), "")
+	if !strings.Contains(body, `"seed":"42"`) {
+		t.Errorf("admins were not sent the seed: %s", body)
+	}
+}
+
+func TestCardDistance(t *testing.T) {
+	a := [][]string{{"a", "b"}, {"c", "d"}}
+	b := [][]string{{"a", "c"}, {"b", "d"}}
+
+	if got := cardDistance(a, a); got != 0 {
+		t.Errorf("distance to itself = %d, want 0", got)
+	}
+	if got := cardDistance(a, b); got != 2 {
+		t.Errorf("distance = %d, want 2", got)
+	}
+	if got := cardDistance(a, [][]string{{"a"}}); got != 4 {
+		t.Errorf("distance to a smaller grid = %d, want 4", got)
+	}
+}
+
+func TestDealtCardsKeepTheirDistance(t *testing.T) {
+	// Eight items on a 3x3 card: a distance of 8 means no item may share a
+	// cell with another card, so only a handful of cards fit
+	theme := newTestTheme("t1", 3, 8)
+	theme.Seed = 7
+	theme.MinCardDistance = 8
+
+	var err error
+	for i := range 50 {
+		if _, err = theme.NewCard(&User{ID: fmt.Sprint("u", i)}); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		t.Fatal("dealt 50 cards that all differ in every cell")
+	}
+	if len(theme.Cards) < 2 || len(theme.Cards) > 8 {
+		t.Errorf("dealt %d cards, want between 2 and 8", len(theme.Cards))
+	}
+
+	cards := slices.Collect(maps.Values(theme.Cards))
+	for i, a := range cards {
+		if v := theme.verifyCard(a); !v.Matches {
+			t.Errorf("card %s does not verify: %s", a.ID, v.Reason)
+		}
+		for _, b := range cards[i+1:] {
+			if d := cardDistance(a.Items, b.Items); d < theme.MinCardDistance {
+				t.Errorf("cards %s and %s differ in %d cells, want at least %d", a.ID, b.ID, d, theme.MinCardDistance)
+			}
+		}
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// changeThemeStateHandler moves a theme through its lifecycle, e.g. from
+// open to locked once every player has their cards.
+func changeThemeStateHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID string `param:"id"`
+		State   string `json:"state"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	if _, known := themeTransitions[req.State]; !known {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown theme state"})
+	}
+
+	if err := changeThemeState(theme, user, req.State); err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	return c.JSON(http.StatusOK, theme)
+}
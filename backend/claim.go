@@ -0,0 +1,123 @@
+package main
+
+import (
+	"cmp"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Claim statuses
+const (
+	ClaimPending  = "pending"
+	ClaimApproved = "approved"
+	ClaimRejected = "rejected"
+)
+
+// Claim is a player calling bingo on their card. Patterns and Valid record
+// what the server found when the claim was made, so admins can see whether
+// the card actually won before approving or rejecting it.
+//
+// Claims do not decide results: wins are recorded from the marks as they
+// happen, so a player who is slow to claim keeps their place. Approving a
+// claim links it to the card's standing wins as the admin's confirmation.
+type Claim struct {
+	ID         string     `json:"id"`
+	ThemeID    string     `json:"theme_id"`
+	CardID     string     `json:"card_id"`
+	UserID     string     `json:"user_id"`
+	TeamID     string     `json:"team_id,omitempty"`
+	Status     string     `json:"status"`
+	Patterns   []string   `json:"patterns,omitempty"`
+	Valid      bool       `json:"valid"`
+	Reason     string     `json:"reason,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+	ResolvedBy string     `json:"resolved_by,omitempty"`
+	// PenaltyUntil blocks the player from claiming again after a false claim
+	PenaltyUntil *time.Time `json:"penalty_until,omitempty"`
+}
+
+// newClaim checks the card against the theme's win patterns and returns a
+// pending claim by user recording the result.
+func (t *Theme) newClaim(card *Card, user *User) *Claim {
+	card.checkBingo(t)
+
+	claim := &Claim{
+		ID:        uuid.New().String(),
+		ThemeID:   t.ID,
+		CardID:    card.ID,
+		UserID:    user.ID,
+		TeamID:    card.TeamID,
+		Status:    ClaimPending,
+		Patterns:  card.Patterns,
+		Valid:     card.IsWinner,
+		CreatedAt: time.Now(),
+	}
+	t.Claims = append(t.Claims, claim)
+
+	return claim
+}
+
+func (t *Theme) getClaim(claimID string) (*Claim, bool) {
+	for _, claim := range t.Claims {
+		if claim.ID == claimID {
+			return claim, true
+		}
+	}
+	return nil, false
+}
+
+// holderID returns the team the claim was made for, or the player who made
+// it.
+func (c *Claim) holderID() string {
+	return cmp.Or(c.TeamID, c.UserID)
+}
+
+// pendingClaim returns the holder's unresolved claim, if any. Teammates share
+// one, so two of them cannot claim the same cards at once.
+func (t *Theme) pendingClaim(holderID string) (*Claim, bool) {
+	for _, claim := range t.Claims {
+		if claim.holderID() == holderID && claim.Status == ClaimPending {
+			return claim, true
+		}
+	}
+	return nil, false
+}
+
+// claimPenalty returns when the holder's latest false-claim penalty ends, if
+// they are still serving one. A team serves the penalty of any member.
+func (t *Theme) claimPenalty(holderID string, now time.Time) (time.Time, bool) {
+	var until time.Time
+	for _, claim := range t.Claims {
+		if claim.holderID() == holderID && claim.PenaltyUntil != nil && claim.PenaltyUntil.After(until) {
+			until = *claim.PenaltyUntil
+		}
+	}
+	return until, until.After(now)
+}
+
+// confirmWins links an approved claim to the standing wins of its card that
+// no earlier claim confirmed.
+func (t *Theme) confirmWins(claim *Claim) {
+	for _, win := range t.Wins {
+		if win.CardID == claim.CardID && win.RevokedAt == nil && win.ClaimID == "" {
+			win.ClaimID = claim.ID
+		}
+	}
+}
+
+// resolve closes a pending claim. A rejected claim starts the theme's
+// penalty when penalize is set.
+func (t *Theme) resolve(claim *Claim, admin *User, status, reason string, penalize bool) {
+	now := time.Now()
+	claim.Status = status
+	claim.Reason = reason
+	claim.ResolvedAt = &now
+	claim.ResolvedBy = admin.ID
+
+	if status == ClaimRejected && penalize && t.ClaimPenaltySeconds > 0 {
+		until := now.Add(time.Duration(t.ClaimPenaltySeconds) * time.Second)
+		claim.PenaltyUntil = &until
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestClaimPenalty(t *testing.T) {
+	theme := newTestTheme("t1", 3, 9)
+	theme.ClaimPenaltySeconds = 60
+	theme.setState(ThemeLive)
+	card := &Card{ID: "c1", UserID: "u1", ThemeID: theme.ID, Items: [][]string{{"a", "b", "c"}, {"d", freeSpaceID, "e"}, {"f", "g", "h"}}}
+	theme.Cards[card.ID] = card
+
+	claim := theme.newClaim(card, &User{ID: "u1"})
+	if claim.Valid {
+		t.Fatal("claim on a card with no marks is valid")
+	}
+	if _, pending := theme.pendingClaim("u1"); !pending {
+		t.Fatal("new claim is not pending")
+	}
+
+	theme.resolve(claim, &User{ID: "admin"}, ClaimRejected, "no bingo", true)
+	if _, pending := theme.pendingClaim("u1"); pending {
+		t.Error("rejected claim is still pending")
+	}
+	if _, blocked := theme.claimPenalty("u1", time.Now()); !blocked {
+		t.Error("penalized player can claim again straight away")
+	}
+	if _, blocked := theme.claimPenalty("u1", time.Now().Add(2*time.Minute)); blocked {
+		t.Error("penalty outlasts the theme's penalty period")
+	}
+}
+
+func TestClaimHandlers(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "admin-discord"}
+	player := &User{ID: "u1"}
+	db.Users = append(db.Users, admin, player)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	theme := newTestTheme("t1", 3, 9)
+	db.Themes = append(db.Themes, theme)
+
+	card, err := theme.NewCard(player)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	server := newTestServer(t)
+	playerToken, adminToken := testToken(t, player.ID), testToken(t, admin.ID)
+	claimsURL := server.URL + "/api/themes/t1/claims"
+
+	if code, body := doRequest(t, http.MethodPost, claimsURL, playerToken, ""); code != http.StatusConflict {
+		t.Fatalf("claim before the theme is live = %d %s, want %d", code, body, http.StatusConflict)
+	}
+
+	theme.setState(ThemeLive)
+	markItems(t, theme, card.Items[0]...)
+	publishWins(theme, admin, "")
+
+	code, body := doRequest(t, http.MethodPost, claimsURL, playerToken, "")
+	if code != http.StatusCreated {
+		t.Fatalf("claim = %d %s", code, body)
+	}
+	var claim Claim
+	if err := json.Unmarshal([]byte(body), &claim); err != nil {
+		t.Fatal(err)
+	}
+	if !claim.Valid || claim.CardID != card.ID {
+		t.Fatalf("claim = %+v, want a valid claim on %s", claim, card.ID)
+	}
+
+	if code, _ := doRequest(t, http.MethodPost, claimsURL, playerToken, ""); code != http.StatusConflict {
+		t.Errorf("second pending claim = %d, want %d", code, http.StatusConflict)
+	}
+
+	approveURL := server.URL + "/api/admin/themes/t1/claims/" + claim.ID + "/approve"
+	if code, _ := doRequest(t, http.MethodPost, approveURL, playerToken, ""); code != http.StatusForbidden {
+		t.Errorf("player approving a claim = %d, want %d", code, http.StatusForbidden)
+	}
+	if code, body := doRequest(t, http.MethodPost, approveURL, adminToken, ""); code != http.StatusOK {
+		t.Fatalf("approve = %d %s", code, body)
+	}
+
+	// Approval confirms the win the marks already recorded
+	if len(theme.Wins) != 1 || theme.Wins[0].ClaimID != claim.ID {
+		t.Errorf("wins = %+v, want one win confirmed by %s", theme.Wins, claim.ID)
+	}
+	if code, _ := doRequest(t, http.MethodPost, approveURL, adminToken, ""); code != http.StatusConflict {
+		t.Errorf("approving twice = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestTeammatesShareClaims(t *testing.T) {
+	useTestStore(t)
+
+	alice, bob := &User{ID: "u1"}, &User{ID: "u2"}
+	db.Users = append(db.Users, alice, bob)
+	theme := newTestTheme("t1", 3, 9)
+	theme.TeamMode = true
+	theme.ClaimPenaltySeconds = 60
+	db.Themes = append(db.Themes, theme)
+
+	red, err := theme.newTeam("Red", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := theme.joinTeam(red, bob); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := theme.NewCard(alice); err != nil {
+		t.Fatal(err)
+	}
+	theme.setState(ThemeLive)
+
+	server := newTestServer(t)
+	claimsURL := server.URL + "/api/themes/t1/claims"
+
+	code, body := doRequest(t, http.MethodPost, claimsURL, testToken(t, alice.ID), "")
+	if code != http.StatusCreated {
+		t.Fatalf("claim = %d %s", code, body)
+	}
+	if code, _ := doRequest(t, http.MethodPost, claimsURL, testToken(t, bob.ID), ""); code != http.StatusConflict {
+		t.Errorf("teammate claiming the same card = %d, want %d", code, http.StatusConflict)
+	}
+	if len(theme.Claims) != 1 {
+		t.Fatalf("claims = %d, want 1", len(theme.Claims))
+	}
+
+	theme.resolve(theme.Claims[0], &User{ID: "admin"}, ClaimRejected, "no bingo", true)
+	if code, _ := doRequest(t, http.MethodPost, claimsURL, testToken(t, bob.ID), ""); code != http.StatusTooManyRequests {
+		t.Errorf("teammate claiming during the team's penalty = %d, want %d", code, http.StatusTooManyRequests)
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// cloneThemeHandler copies a theme's items and settings into a new draft.
+// Items get new IDs and start unmarked; cards, wins and claims are not
+// copied. Any theme field in the body overrides the copied value.
+func cloneThemeHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	source, found := getThemeByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	fields := themeFields{
+		Name:          source.Name + " (copy)",
+		Description:   source.Description,
+		Items:         source.itemSpecs(),
+		ThemeSettings: source.settings(),
+		State:         ThemeDraft,
+	}
+
+	var req themeOverrides
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	req.apply(&fields)
+
+	theme, err := newTheme(fields.Name, fields.Description, fields.Items, fields.ThemeSettings, fields.State)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	addTheme(theme, user)
+
+	return c.JSON(http.StatusCreated, theme)
+}
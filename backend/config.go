@@ -13,7 +13,9 @@ import (
 )
 
 var (
-	db           Database
+	db           *Database
+	store        Store
+	events       *eventLog
 	jwtSecret    = []byte(cmp.Or(os.Getenv("JWT_SECRET"), uuid.New().String()))
 	discordOAuth = &oauth2.Config{
 		ClientID:     cmp.Or(os.Getenv("DISCORD_CLIENT_ID"), ""),
@@ -30,6 +32,9 @@ var (
 			return true // Allow connections from any origin
 		},
 	}
-	connections = make(map[*websocket.Conn]bool)
+	// connections holds the connected WebSocket clients
+	connections = make(map[*websocket.Conn]*client)
 	connMutex   sync.RWMutex
+	// dbMutex guards db and every Theme, Item, Card and User reachable from it
+	dbMutex sync.RWMutex
 )
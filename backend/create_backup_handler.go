@@ -0,0 +1,17 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func createBackupHandler(c echo.Context) error {
+	backup, err := createBackup(backupManual)
+	if err != nil {
+		c.Logger().Error("Error creating backup:", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create backup"})
+	}
+
+	return c.JSON(http.StatusCreated, backup)
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// createRoomHandler adds a room. A theme can be made active in it at
+// creation or later through setRoomThemeHandler.
+func createRoomHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		Name          string `json:"name"`
+		ActiveThemeID string `json:"active_theme_id"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Room name is required"})
+	}
+
+	room := &Room{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		CreatedAt: time.Now(),
+	}
+
+	if err := checkRoomTheme(room, req.ActiveThemeID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	room.ActiveThemeID = req.ActiveThemeID
+
+	db.Rooms = append(db.Rooms, room)
+	if err := store.SaveRoom(room); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventRoomCreated, user, room.ActiveThemeID, room.ID, nil, room)
+
+	broadcastUpdate("room_created", room)
+
+	return c.JSON(http.StatusCreated, room)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/labstack/echo/v4"
+)
+
+// createSeasonHandler groups themes into a season. Without scoring rules
+// the season uses the default ones.
+func createSeasonHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		Name     string        `json:"name"`
+		ThemeIDs []string      `json:"theme_ids"`
+		Scoring  *ScoringRules `json:"scoring"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Season name is required"})
+	}
+
+	if err := validateSeasonThemes(req.ThemeIDs); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if req.Scoring != nil {
+		if err := req.Scoring.validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	season := &Season{
+		ID:        uuid.New().String(),
+		Name:      req.Name,
+		ThemeIDs:  req.ThemeIDs,
+		Scoring:   req.Scoring,
+		CreatedAt: time.Now(),
+	}
+	if season.ThemeIDs == nil {
+		season.ThemeIDs = []string{}
+	}
+
+	db.Seasons = append(db.Seasons, season)
+	if err := store.SaveSeason(season); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventSeasonCreated, user, "", season.ID, nil, season)
+
+	broadcastUpdate("season_created", season)
+
+	return c.JSON(http.StatusCreated, season)
+}
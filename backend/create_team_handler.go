@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// createTeamHandler starts a new team in a team theme with the current
+// user as its first member.
+func createTeamHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID string `param:"id"`
+		Name    string `json:"name"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	before := eventState(theme)
+	team, err := theme.newTeam(req.Name, user)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	saveTeams(theme, user, before)
+
+	return c.JSON(http.StatusCreated, team)
+}
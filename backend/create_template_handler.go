@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// createTemplateHandler saves a template from the items and settings in
+// the request body.
+func createTemplateHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	fields := themeFields{ThemeSettings: ThemeSettings{GridSize: defaultGridSize}}
+	var req themeOverrides
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	req.apply(&fields)
+
+	template, err := newTemplate(fields)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	addTemplate(template, user)
+
+	return c.JSON(http.StatusCreated, template)
+}
@@ -1,12 +1,11 @@
 package main
 
 import (
+	"cmp"
 	"log/slog"
 	"net/http"
 	"slices"
-	"time"
 
-	"github.com/google/uuid"
 	"github.com/labstack/echo/v4"
 )
 
@@ -17,9 +16,19 @@ func createThemeHandler(c echo.Context) error {
 	}
 
 	var request struct {
-		Name        string   `json:"name"`
-		Description string   `json:"description"`
-		Items       []string `json:"items"`
+		Name                string       `json:"name"`
+		Description         string       `json:"description"`
+		Items               []itemSpec   `json:"items"`
+		GridSize            int          `json:"grid_size"`
+		WinPatterns         []WinPattern `json:"win_patterns"`
+		MarkingMode         string       `json:"marking_mode"`
+		ClaimPenaltySeconds int          `json:"claim_penalty_seconds"`
+		MinCardDistance     int          `json:"min_card_distance"`
+		LayoutRules         []LayoutRule `json:"layout_rules"`
+		MaxCardsPerPlayer   int          `json:"max_cards_per_player"`
+		MaxRerolls          int          `json:"max_rerolls"`
+		State               string       `json:"state"`
+		TeamMode            bool         `json:"team_mode"`
 	}
 
 	if err := c.Bind(&request); err != nil {
@@ -27,33 +36,25 @@ func createThemeHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
 	}
 
-	if len(request.Items) < 25 {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Theme must have at least 25 items"})
+	settings := ThemeSettings{
+		GridSize:            cmp.Or(request.GridSize, defaultGridSize),
+		WinPatterns:         request.WinPatterns,
+		MarkingMode:         request.MarkingMode,
+		ClaimPenaltySeconds: request.ClaimPenaltySeconds,
+		MinCardDistance:     request.MinCardDistance,
+		LayoutRules:         request.LayoutRules,
+		MaxCardsPerPlayer:   request.MaxCardsPerPlayer,
+		MaxRerolls:          request.MaxRerolls,
+		TeamMode:            request.TeamMode,
 	}
 
-	items := make([]*Item, len(request.Items))
-	for i, itemName := range request.Items {
-		items[i] = &Item{
-			ID:   uuid.New().String(),
-			Name: itemName,
-		}
+	// Themes open for cards straight away unless created as drafts
+	theme, err := newTheme(request.Name, request.Description, request.Items, settings, cmp.Or(request.State, ThemeOpen))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
-	theme := &Theme{
-		ID:          uuid.New().String(),
-		Name:        request.Name,
-		Description: request.Description,
-		Items:       items,
-		Cards:       make(map[string]*Card),
-		CreatedAt:   time.Now(),
-	}
-
-	db.Themes = append(db.Themes, theme)
-	if err := saveDatabase(); err != nil {
-		c.Logger().Error("Error saving database:", err)
-	}
-
-	broadcastUpdate("theme_created", theme)
+	addTheme(theme, user)
 
 	return c.JSON(http.StatusCreated, theme)
 }
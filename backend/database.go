@@ -1,7 +1,6 @@
 package main
 
 import (
-	"encoding/json"
 	"log"
 	"os"
 	"slices"
@@ -9,66 +8,75 @@ import (
 )
 
 type Database struct {
-	Users           []*User  `json:"users"`
-	BingoCards      []*Card  `json:"bingo_cards"`
+	SchemaVersion int     `json:"schema_version"`
+	Users         []*User `json:"users"`
+	// Deprecated: cards live in Theme.Cards. Only read so migrations can move old cards.
+	BingoCards      []*Card  `json:"bingo_cards,omitempty"`
 	AdminDiscordIDs []string `json:"admin_discord_ids"`
 	Themes          []*Theme `json:"themes"`
 	ActiveThemeID   string   `json:"active_theme_id"`
+	// Rooms are the extra rooms created by admins; the default room is
+	// ActiveThemeID
+	Rooms   []*Room   `json:"rooms"`
+	Seasons []*Season `json:"seasons"`
+	// Templates are saved starting points for new themes
+	Templates []*ThemeTemplate `json:"templates"`
 }
 
 func loadDatabase() error {
-	data, err := os.ReadFile("data/database.json")
-	if err != nil {
-		log.Println("Database file not found, creating new one")
-		db = Database{
-			Users:           []*User{},
-			BingoCards:      []*Card{},
-			AdminDiscordIDs: []string{},
-			Themes:          []*Theme{},
-			ActiveThemeID:   "",
-		}
-		return saveDatabase()
+	var err error
+	if store, err = newStore(); err != nil {
+		return err
 	}
 
-	if err := json.Unmarshal(data, &db); err != nil {
-		log.Fatal("Error parsing database:", err)
+	if db, err = store.Load(); err != nil {
+		return err
 	}
 
-	// Initialize fields if they don't exist
-	if db.AdminDiscordIDs == nil {
-		db.AdminDiscordIDs = []string{}
+	reports, err := migrateDatabase(db)
+	if err != nil {
+		return err
+	}
+	if len(reports) > 0 {
+		for _, report := range reports {
+			log.Printf("Applied migration v%d: %s (%d changes)", report.Version, report.Description, len(report.Changes))
+			for _, change := range report.Changes {
+				log.Printf("  %s", change)
+			}
+		}
+		if err := store.Replace(db); err != nil {
+			return err
+		}
 	}
 
-	// Add admin IDs from environment variable
-	envAdminIDs := os.Getenv("ADMIN_DISCORD_IDS")
-	if envAdminIDs == "" {
+	if !addEnvAdminIDs(db) {
 		return nil
 	}
 
-	envIDs := strings.SplitSeq(envAdminIDs, ",")
+	return store.SaveAdminDiscordIDs(db.AdminDiscordIDs)
+}
+
+// addEnvAdminIDs adds the admin IDs from the ADMIN_DISCORD_IDS environment
+// variable to d and reports whether any were missing.
+func addEnvAdminIDs(d *Database) bool {
+	// Initialize fields if they don't exist
+	if d.AdminDiscordIDs == nil {
+		d.AdminDiscordIDs = []string{}
+	}
+
+	changed := false
+	envIDs := strings.SplitSeq(os.Getenv("ADMIN_DISCORD_IDS"), ",")
 	for id := range envIDs {
 		id = strings.TrimSpace(id)
 		if id != "" {
 			// Check if already exists
-			exists := slices.Contains(db.AdminDiscordIDs, id)
+			exists := slices.Contains(d.AdminDiscordIDs, id)
 			if !exists {
-				db.AdminDiscordIDs = append(db.AdminDiscordIDs, id)
+				d.AdminDiscordIDs = append(d.AdminDiscordIDs, id)
+				changed = true
 			}
 		}
 	}
 
-	return saveDatabase()
-}
-
-func saveDatabase() error {
-	if err := os.MkdirAll("data", 0755); err != nil {
-		return err
-	}
-
-	data, err := json.MarshalIndent(db, "", "  ")
-	if err != nil {
-		return err
-	}
-
-	return os.WriteFile("data/database.json", data, 0644)
+	return changed
 }
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// daubItemHandler toggles an item on the player's own cards in daub mode,
+// either on the card given by card_id or on every card holding the item.
+// Items can only be daubed once they have been called.
+func daubItemHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID string `param:"themeId"`
+		ItemID  string `param:"itemId"`
+		CardID  string `json:"card_id"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	if theme.State != ThemeLive {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Items can only be daubed while the theme is live"})
+	}
+
+	if !theme.daubing() {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Theme is not in daub mode"})
+	}
+
+	holderID, err := theme.holderID(user)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	cards := slices.DeleteFunc(theme.userCards(holderID), func(card *Card) bool {
+		return req.CardID != "" && card.ID != req.CardID || !card.hasItem(req.ItemID)
+	})
+	if len(cards) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Item is not on your card"})
+	}
+
+	// Daub the item everywhere unless it is already daubed on every card
+	undaub := !slices.ContainsFunc(cards, func(card *Card) bool {
+		return !slices.Contains(card.Daubed, req.ItemID)
+	})
+
+	if !undaub {
+		item, found := theme.GetItem(req.ItemID)
+		if !found || !item.Marked {
+			return c.JSON(http.StatusConflict, map[string]string{"error": "Item has not been called"})
+		}
+	}
+
+	befores := make([]json.RawMessage, len(cards))
+	for i, card := range cards {
+		befores[i] = eventState(card)
+
+		if undaub {
+			card.Daubed = slices.DeleteFunc(card.Daubed, func(id string) bool { return id == req.ItemID })
+		} else if !slices.Contains(card.Daubed, req.ItemID) {
+			card.Daubed = append(card.Daubed, req.ItemID)
+		}
+	}
+
+	publishWins(theme, user, req.ItemID)
+
+	for i, card := range cards {
+		if err := store.SaveCard(card); err != nil {
+			c.Logger().Error("Error saving database:", err)
+		}
+		recordEvent(EventCardDaubed, user, theme.ID, req.ItemID, befores[i], card)
+
+		broadcastThemeUpdate(theme.ID, "card_updated", card)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"cards": cards,
+	})
+}
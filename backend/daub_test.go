@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestDaubedCardsWin(t *testing.T) {
+	theme := newTestTheme("t1", 3, 9)
+	theme.MarkingMode = MarkingDaub
+	theme.setState(ThemeLive)
+	card := &Card{ID: "c1", UserID: "u1", ThemeID: theme.ID, Items: [][]string{{"a", "b", "c"}, {"d", freeSpaceID, "e"}, {"f", "g", "h"}}}
+	theme.Cards[card.ID] = card
+
+	// Called but not daubed
+	markItems(t, theme, "a", "b", "c", "d", "e", "f", "g", "h")
+	if won, _, _ := theme.updateWins("", "", time.Now()); len(won) != 0 {
+		t.Fatalf("card won without being daubed")
+	}
+
+	card.Daubed = []string{"a", "b", "c"}
+	if won, _, _ := theme.updateWins("", "", time.Now()); len(won) != 1 {
+		t.Fatalf("got %d wins for a daubed row, want 1", len(won))
+	}
+
+	// A daub no longer counts once its item is uncalled
+	item, _ := theme.GetItem("c")
+	item.Marked = false
+	if _, revoked, _ := theme.updateWins("", "", time.Now()); len(revoked) != 1 {
+		t.Fatalf("got %d revoked wins, want 1", len(revoked))
+	}
+}
+
+func TestDaubItemHandler(t *testing.T) {
+	useTestStore(t)
+
+	user := &User{ID: "u1"}
+	db.Users = append(db.Users, user)
+	theme := newTestTheme("t1", 3, 9)
+	theme.MarkingMode = MarkingDaub
+	db.Themes = append(db.Themes, theme)
+
+	card, err := theme.NewCard(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theme.setState(ThemeLive)
+
+	server := newTestServer(t)
+	token := testToken(t, user.ID)
+	itemID := card.Items[0][0]
+	url := server.URL + "/api/themes/t1/items/" + itemID + "/daub"
+
+	if code, body := doRequest(t, http.MethodPost, url, token, ""); code != http.StatusConflict {
+		t.Fatalf("daubing an uncalled item = %d %s, want %d", code, body, http.StatusConflict)
+	}
+
+	markItems(t, theme, itemID)
+	if code, body := doRequest(t, http.MethodPost, url, token, ""); code != http.StatusOK {
+		t.Fatalf("daub = %d %s", code, body)
+	}
+	if !slices.Contains(card.Daubed, itemID) {
+		t.Fatalf("item not daubed: %v", card.Daubed)
+	}
+
+	// Daubing again takes the daub back off
+	if code, body := doRequest(t, http.MethodPost, url, token, ""); code != http.StatusOK {
+		t.Fatalf("undaub = %d %s", code, body)
+	}
+	if slices.Contains(card.Daubed, itemID) {
+		t.Fatalf("item still daubed: %v", card.Daubed)
+	}
+
+	other := "/api/themes/t1/items/zz/daub"
+	if code, _ := doRequest(t, http.MethodPost, server.URL+other, token, ""); code != http.StatusBadRequest {
+		t.Errorf("daubing an item not on the card = %d, want %d", code, http.StatusBadRequest)
+	}
+}
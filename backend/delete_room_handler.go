@@ -0,0 +1,38 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// deleteRoomHandler removes a room. Its active theme is left untouched and
+// can be made active elsewhere. The default room cannot be deleted.
+func deleteRoomHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+	roomID := c.Param("roomId")
+
+	if roomID == defaultRoomID {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Cannot delete the default room"})
+	}
+
+	i := slices.IndexFunc(db.Rooms, func(r *Room) bool { return r.ID == roomID })
+	if i < 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Room not found"})
+	}
+
+	room := db.Rooms[i]
+	db.Rooms = slices.Delete(db.Rooms, i, i+1)
+	if err := store.DeleteRoom(roomID); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventRoomDeleted, user, room.ActiveThemeID, roomID, room, nil)
+
+	broadcastUpdate("room_deleted", map[string]any{
+		"id":   room.ID,
+		"name": room.Name,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Room deleted successfully"})
+}
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// deleteSeasonHandler removes a season. Its themes and their results are
+// kept and still count towards the all-time leaderboard.
+func deleteSeasonHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+	seasonID := c.Param("id")
+
+	i := slices.IndexFunc(db.Seasons, func(s *Season) bool { return s.ID == seasonID })
+	if i < 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Season not found"})
+	}
+
+	season := db.Seasons[i]
+	db.Seasons = slices.Delete(db.Seasons, i, i+1)
+	if err := store.DeleteSeason(seasonID); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventSeasonDeleted, user, "", seasonID, season, nil)
+
+	broadcastUpdate("season_deleted", map[string]any{
+		"id":   season.ID,
+		"name": season.Name,
+	})
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Season deleted successfully"})
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// deleteTemplateHandler removes a template. Themes created from it are
+// not affected.
+func deleteTemplateHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+	templateID := c.Param("id")
+
+	i := slices.IndexFunc(db.Templates, func(t *ThemeTemplate) bool { return t.ID == templateID })
+	if i < 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Template not found"})
+	}
+
+	template := db.Templates[i]
+	db.Templates = slices.Delete(db.Templates, i, i+1)
+	if err := store.DeleteTemplate(templateID); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventTemplateDeleted, user, "", templateID, template, nil)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Template deleted successfully"})
+}
@@ -13,16 +13,10 @@ func deleteThemeHandler(c echo.Context) error {
 		return c.JSON(http.StatusForbidden, map[string]string{"error": "Admin access required"})
 	}
 
-	defer func() {
-		if err := saveDatabase(); err != nil {
-			c.Logger().Error("Error saving database:", err)
-		}
-	}()
-
 	themeID := c.Param("id")
 
 	// Don't allow deleting the active theme
-	if db.ActiveThemeID == themeID {
+	if _, ok := themeRoom(themeID); ok {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Cannot delete the active theme. Set a different theme as active first."})
 	}
 
@@ -33,6 +27,10 @@ func deleteThemeHandler(c echo.Context) error {
 			deletedTheme := theme
 			// Remove theme from slice
 			db.Themes = append(db.Themes[:i], db.Themes[i+1:]...)
+			if err := store.DeleteTheme(themeID); err != nil {
+				c.Logger().Error("Error saving database:", err)
+			}
+			recordEvent(EventThemeDeleted, user, themeID, themeID, deletedTheme, nil)
 
 			broadcastUpdate("theme_deleted", map[string]any{
 				"id":   deletedTheme.ID,
@@ -43,15 +41,5 @@ func deleteThemeHandler(c echo.Context) error {
 		}
 	}
 
-	// Remove all cards associated with the deleted theme
-	var remainingCards []*Card
-	for _, card := range db.BingoCards {
-		if card.ThemeID != themeID {
-			remainingCards = append(remainingCards, card)
-		}
-	}
-
-	db.BingoCards = remainingCards
-
 	return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
 }
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"net/http"
+	"os"
+
+	"github.com/labstack/echo/v4"
+)
+
+func downloadBackupHandler(c echo.Context) error {
+	name := c.Param("name")
+
+	path, ok := backupPath(name)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid backup name"})
+	}
+
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Backup not found"})
+	}
+
+	return c.Attachment(path, name)
+}
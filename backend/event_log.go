@@ -0,0 +1,445 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	// EventSnapshot records the full database. It is written as the first
+	// event of a new log so replay has a starting point.
+	EventSnapshot           EventType = "snapshot"
+	EventDatabaseRestored   EventType = "database_restored"
+	EventUserCreated        EventType = "user_created"
+	EventThemeCreated       EventType = "theme_created"
+	EventThemeUpdated       EventType = "theme_updated"
+	EventThemeDeleted       EventType = "theme_deleted"
+	EventItemToggled        EventType = "item_toggled"
+	EventItemDrawn          EventType = "item_drawn"
+	EventCardCreated        EventType = "card_created"
+	EventCardDaubed         EventType = "card_daubed"
+	EventCardRerolled       EventType = "card_rerolled"
+	EventClaimSubmitted     EventType = "claim_submitted"
+	EventClaimResolved      EventType = "claim_resolved"
+	EventActiveThemeChanged EventType = "active_theme_changed"
+	EventRoomCreated        EventType = "room_created"
+	EventRoomUpdated        EventType = "room_updated"
+	EventRoomDeleted        EventType = "room_deleted"
+	EventSeasonCreated      EventType = "season_created"
+	EventSeasonUpdated      EventType = "season_updated"
+	EventSeasonDeleted      EventType = "season_deleted"
+	EventTemplateCreated    EventType = "template_created"
+	EventTemplateDeleted    EventType = "template_deleted"
+)
+
+// Event is one mutation of the game state. Before and After hold the JSON
+// encoding of the changed entity; themes are recorded without their cards.
+type Event struct {
+	Seq       int64           `json:"seq"`
+	Type      EventType       `json:"type"`
+	At        time.Time       `json:"at"`
+	ActorID   string          `json:"actor_id,omitempty"`
+	ThemeID   string          `json:"theme_id,omitempty"`
+	SubjectID string          `json:"subject_id,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+}
+
+// eventLog is an append-only JSON Lines file of Events.
+type eventLog struct {
+	mu   sync.Mutex
+	path string
+	file *os.File
+	seq  int64
+}
+
+func openEventLog(path string) (*eventLog, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	l := &eventLog{path: path}
+	if err := l.scan(func(e *Event) bool {
+		l.seq = e.Seq
+		return true
+	}); err != nil {
+		return nil, err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	l.file = file
+
+	return l, nil
+}
+
+// empty reports whether no event has been written yet.
+func (l *eventLog) empty() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.seq == 0
+}
+
+// append assigns the next sequence number to e and writes it. Writes are
+// not fsynced individually; Close syncs the file.
+func (l *eventLog) append(e Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	e.Seq = l.seq + 1
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	if _, err := l.file.Write(append(data, '\n')); err != nil {
+		return err
+	}
+
+	l.seq = e.Seq
+	return nil
+}
+
+// scan calls fn for every event in order until fn returns false. It does
+// not take the log's lock, so a last line without its newline is an event
+// still being appended and is skipped.
+func (l *eventLog) scan(fn func(*Event) bool) error {
+	file, err := os.Open(l.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+
+	line := 0
+	for {
+		data, err := reader.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		line++
+		if len(bytes.TrimSpace(data)) == 0 {
+			continue
+		}
+
+		var e Event
+		if err := json.Unmarshal(data, &e); err != nil {
+			return fmt.Errorf("event log line %d: %w", line, err)
+		}
+		if !fn(&e) {
+			return nil
+		}
+	}
+}
+
+func (l *eventLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return errors.Join(l.file.Sync(), l.file.Close())
+}
+
+// recordEvent appends an event to the global log. before and after are
+// marshalled immediately, so callers may keep mutating them afterwards.
+// Failures are logged rather than returned, like failed saves.
+func recordEvent(eventType EventType, actor *User, themeID, subjectID string, before, after any) {
+	if events == nil {
+		return
+	}
+
+	e := Event{
+		Type:      eventType,
+		ThemeID:   themeID,
+		SubjectID: subjectID,
+	}
+	if actor != nil {
+		e.ActorID = actor.ID
+	}
+
+	e.Before = eventState(before)
+	e.After = eventState(after)
+
+	if err := events.append(e); err != nil {
+		log.Println("Error recording event:", err)
+	}
+}
+
+// eventState encodes v for an event. Call it before mutating an entity to
+// capture its "before" state.
+func eventState(v any) json.RawMessage {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case json.RawMessage:
+		return v
+	case *Theme:
+		if v.Cards != nil {
+			return eventState(themeWithoutCards(v))
+		}
+	}
+
+	data, err := json.Marshal(v)
+	if err != nil {
+		log.Println("Error encoding event state:", err)
+		return nil
+	}
+	return data
+}
+
+// themeWithoutCards returns a shallow copy of t without its cards, which
+// are recorded by their own events.
+func themeWithoutCards(t *Theme) *Theme {
+	c := *t
+	c.Cards = nil
+	return &c
+}
+
+// replayEvents rebuilds a Database by applying events in order. The first
+// event must be a snapshot or restore.
+func replayEvents(l *eventLog) (*Database, error) {
+	var d *Database
+	var replayErr error
+
+	err := l.scan(func(e *Event) bool {
+		if d == nil && e.Type != EventSnapshot && e.Type != EventDatabaseRestored {
+			replayErr = fmt.Errorf("event %d: log does not start with a snapshot", e.Seq)
+			return false
+		}
+		if replayErr = applyEvent(&d, e); replayErr != nil {
+			replayErr = fmt.Errorf("event %d (%s): %w", e.Seq, e.Type, replayErr)
+			return false
+		}
+		return true
+	})
+	if err := errors.Join(err, replayErr); err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, errors.New("event log is empty")
+	}
+
+	return d, nil
+}
+
+func applyEvent(dp **Database, e *Event) error {
+	switch e.Type {
+	case EventSnapshot, EventDatabaseRestored:
+		d := newDatabase()
+		if err := json.Unmarshal(e.After, d); err != nil {
+			return err
+		}
+		*dp = d
+		return nil
+	}
+
+	d := *dp
+	switch e.Type {
+	case EventUserCreated:
+		var user User
+		if err := json.Unmarshal(e.After, &user); err != nil {
+			return err
+		}
+		d.Users = append(d.Users, &user)
+
+	case EventThemeCreated:
+		var theme Theme
+		if err := json.Unmarshal(e.After, &theme); err != nil {
+			return err
+		}
+		theme.Cards = make(map[string]*Card)
+		d.Themes = append(d.Themes, &theme)
+
+	case EventThemeUpdated:
+		theme, ok := findTheme(d, e.ThemeID)
+		if !ok {
+			return fmt.Errorf("theme %s not found", e.ThemeID)
+		}
+		cards := theme.Cards
+		*theme = Theme{}
+		if err := json.Unmarshal(e.After, theme); err != nil {
+			return err
+		}
+		theme.Cards = cards
+		theme.updateWins(e.ActorID, "", e.At)
+
+	case EventThemeDeleted:
+		for i, theme := range d.Themes {
+			if theme.ID == e.ThemeID {
+				d.Themes = append(d.Themes[:i], d.Themes[i+1:]...)
+				break
+			}
+		}
+
+	case EventItemToggled, EventItemDrawn:
+		theme, ok := findTheme(d, e.ThemeID)
+		if !ok {
+			return fmt.Errorf("theme %s not found", e.ThemeID)
+		}
+		item, ok := theme.GetItem(e.SubjectID)
+		if !ok {
+			return fmt.Errorf("item %s not found", e.SubjectID)
+		}
+		if err := json.Unmarshal(e.After, item); err != nil {
+			return err
+		}
+		if e.Type == EventItemDrawn && theme.Caller != nil {
+			theme.Caller.replayDraw(item.ID, e.At)
+		}
+		theme.updateWins(e.ActorID, item.ID, e.At)
+
+	case EventCardCreated:
+		theme, ok := findTheme(d, e.ThemeID)
+		if !ok {
+			return fmt.Errorf("theme %s not found", e.ThemeID)
+		}
+		var card Card
+		if err := json.Unmarshal(e.After, &card); err != nil {
+			return err
+		}
+		if theme.Cards == nil {
+			theme.Cards = make(map[string]*Card)
+		}
+		theme.Cards[card.ID] = &card
+
+	case EventCardDaubed, EventCardRerolled:
+		theme, ok := findTheme(d, e.ThemeID)
+		if !ok {
+			return fmt.Errorf("theme %s not found", e.ThemeID)
+		}
+		var card Card
+		if err := json.Unmarshal(e.After, &card); err != nil {
+			return err
+		}
+		theme.Cards[card.ID] = &card
+		theme.updateWins(e.ActorID, e.SubjectID, e.At)
+
+	case EventClaimSubmitted, EventClaimResolved:
+		theme, ok := findTheme(d, e.ThemeID)
+		if !ok {
+			return fmt.Errorf("theme %s not found", e.ThemeID)
+		}
+		var claim Claim
+		if err := json.Unmarshal(e.After, &claim); err != nil {
+			return err
+		}
+		if existing, ok := theme.getClaim(claim.ID); ok {
+			*existing = claim
+		} else {
+			theme.Claims = append(theme.Claims, &claim)
+		}
+
+	case EventActiveThemeChanged:
+		if err := json.Unmarshal(e.After, &d.ActiveThemeID); err != nil {
+			return err
+		}
+
+	case EventRoomCreated:
+		var room Room
+		if err := json.Unmarshal(e.After, &room); err != nil {
+			return err
+		}
+		d.Rooms = append(d.Rooms, &room)
+
+	case EventRoomUpdated:
+		i := slices.IndexFunc(d.Rooms, func(r *Room) bool { return r.ID == e.SubjectID })
+		if i < 0 {
+			return fmt.Errorf("room %s not found", e.SubjectID)
+		}
+		var room Room
+		if err := json.Unmarshal(e.After, &room); err != nil {
+			return err
+		}
+		d.Rooms[i] = &room
+
+	case EventRoomDeleted:
+		d.Rooms = slices.DeleteFunc(d.Rooms, func(r *Room) bool { return r.ID == e.SubjectID })
+
+	case EventSeasonCreated:
+		var season Season
+		if err := json.Unmarshal(e.After, &season); err != nil {
+			return err
+		}
+		d.Seasons = append(d.Seasons, &season)
+
+	case EventSeasonUpdated:
+		i := slices.IndexFunc(d.Seasons, func(s *Season) bool { return s.ID == e.SubjectID })
+		if i < 0 {
+			return fmt.Errorf("season %s not found", e.SubjectID)
+		}
+		var season Season
+		if err := json.Unmarshal(e.After, &season); err != nil {
+			return err
+		}
+		d.Seasons[i] = &season
+
+	case EventSeasonDeleted:
+		d.Seasons = slices.DeleteFunc(d.Seasons, func(s *Season) bool { return s.ID == e.SubjectID })
+
+	case EventTemplateCreated:
+		var template ThemeTemplate
+		if err := json.Unmarshal(e.After, &template); err != nil {
+			return err
+		}
+		d.Templates = append(d.Templates, &template)
+
+	case EventTemplateDeleted:
+		d.Templates = slices.DeleteFunc(d.Templates, func(t *ThemeTemplate) bool { return t.ID == e.SubjectID })
+
+	default:
+		return fmt.Errorf("unknown event type %q", e.Type)
+	}
+
+	return nil
+}
+
+func findTheme(d *Database, themeID string) (*Theme, bool) {
+	for _, theme := range d.Themes {
+		if theme.ID == themeID {
+			return theme, true
+		}
+	}
+	return nil, false
+}
+
+// rebuildFromEvents replays the configured event log and writes the
+// resulting database to outPath without touching the live store.
+func rebuildFromEvents(logPath, outPath string) error {
+	l := &eventLog{path: logPath}
+	d, err := replayEvents(l)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(d, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(outPath, data, nil)
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// useTestEventLog opens an event log in a temporary directory as the global
+// log. Call it after useDatabase, which restores the previous log.
+func useTestEventLog(t *testing.T) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "events.jsonl")
+	l, err := openEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	events = l
+
+	return path
+}
+
+func TestReplayEvents(t *testing.T) {
+	useTestStore(t)
+	path := useTestEventLog(t)
+
+	recordEvent(EventSnapshot, nil, "", "", nil, db)
+
+	user := &User{ID: "u1"}
+	recordEvent(EventUserCreated, user, "", user.ID, nil, user)
+
+	theme := newTestTheme("t1", 5, 25)
+	recordEvent(EventThemeCreated, user, theme.ID, theme.ID, nil, theme)
+
+	card, err := theme.NewCard(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	recordEvent(EventCardCreated, user, theme.ID, card.ID, nil, card)
+
+	// Call the card's first column
+	for _, row := range card.Items {
+		item, ok := theme.GetItem(row[0])
+		if !ok {
+			t.Fatalf("card item %s not in theme", row[0])
+		}
+		before := eventState(item)
+		item.Marked = true
+		recordEvent(EventItemToggled, user, theme.ID, item.ID, before, item)
+	}
+	recordEvent(EventActiveThemeChanged, user, theme.ID, "", "", theme.ID)
+
+	if err := events.Close(); err != nil {
+		t.Fatal(err)
+	}
+	l, err := openEventLog(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	if l.seq != 10 {
+		t.Errorf("reopened log at sequence %d, want 10", l.seq)
+	}
+
+	replayed, err := replayEvents(l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if replayed.ActiveThemeID != theme.ID || len(replayed.Users) != 1 || len(replayed.Themes) != 1 {
+		t.Fatalf("replay lost state: %+v", replayed)
+	}
+	if got := replayed.Themes[0].Cards[card.ID]; got == nil || !got.IsWinner {
+		t.Errorf("card replayed as %+v, want a winner", got)
+	}
+}
+
+func TestGetEventsHandlerItemFilter(t *testing.T) {
+	useTestStore(t)
+	useTestEventLog(t)
+
+	admin := &User{ID: "admin", DiscordID: "d-admin"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+
+	item := &Item{ID: "a"}
+	recordEvent(EventItemToggled, admin, "t1", item.ID, nil, item)
+	recordEvent(EventItemDrawn, nil, "t1", item.ID, nil, item)
+	recordEvent(EventCardDaubed, &User{ID: "u1"}, "t1", item.ID, nil, &Card{ID: "c1"})
+	recordEvent(EventItemToggled, admin, "t1", "b", nil, &Item{ID: "b"})
+	recordEvent(EventCardCreated, admin, "t1", item.ID, nil, &Card{ID: "a"})
+
+	server := newTestServer(t)
+	code, body := doRequest(t, http.MethodGet, server.URL+"/api/admin/events?item_id=a", testToken(t, admin.ID), "")
+	if code != http.StatusOK {
+		t.Fatalf("events = %d %s", code, body)
+	}
+
+	var resp struct {
+		Events []*Event `json:"events"`
+	}
+	if err := json.Unmarshal([]byte(body), &resp); err != nil {
+		t.Fatal(err)
+	}
+
+	var types []EventType
+	for _, e := range resp.Events {
+		types = append(types, e.Type)
+	}
+	want := []EventType{EventCardDaubed, EventItemDrawn, EventItemToggled}
+	if len(types) != len(want) {
+		t.Fatalf("got events %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("got events %v, want %v newest first", types, want)
+		}
+	}
+}
+
+func TestScanSkipsEventBeingWritten(t *testing.T) {
+	useTestStore(t)
+	path := useTestEventLog(t)
+
+	recordEvent(EventItemToggled, nil, "t1", "a", nil, &Item{ID: "a"})
+
+	// The start of an event another goroutine is still appending
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"seq":2,"type":"item_tog`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	var seqs []int64
+	err = events.scan(func(e *Event) bool {
+		seqs = append(seqs, e.Seq)
+		return true
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(seqs) != 1 || seqs[0] != 1 {
+		t.Errorf("scanned events %v, want only the complete event 1", seqs)
+	}
+}
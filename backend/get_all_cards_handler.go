@@ -25,8 +25,13 @@ func getAllCardsHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Theme not found"})
 	}
 
-	return c.JSON(http.StatusOK, map[string]any{
+	response := map[string]any{
 		"cards": theme.Cards,
 		"users": db.Users,
-	})
+	}
+	if theme.TeamMode {
+		response["teams"] = teamCardGroups(theme)
+	}
+
+	return c.JSON(http.StatusOK, response)
 }
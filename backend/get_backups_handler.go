@@ -0,0 +1,19 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func getBackupsHandler(c echo.Context) error {
+	backups, err := listBackups()
+	if err != nil {
+		c.Logger().Error("Error listing backups:", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to list backups"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"backups": backups,
+	})
+}
@@ -18,8 +18,15 @@ func getCardByUserIdHandler(c echo.Context) error {
 		})
 	}
 
-	if card, ok := theme.Cards[user.ID]; ok {
-		return c.JSON(http.StatusOK, card)
+	holderID, err := theme.holderID(user)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	// Players holding several cards get their first one; team members get
+	// their team's
+	if cards := theme.userCards(holderID); len(cards) > 0 {
+		return c.JSON(http.StatusOK, cards[0])
 	}
 
 	card, err := theme.NewCard(user)
@@ -27,9 +34,10 @@ func getCardByUserIdHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
-	if err := saveDatabase(); err != nil {
+	if err := store.SaveCard(card); err != nil {
 		c.Logger().Error("Error saving database:", err)
 	}
+	recordEvent(EventCardCreated, user, theme.ID, card.ID, nil, card)
 
 	return c.JSON(http.StatusOK, card)
 }
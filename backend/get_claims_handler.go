@@ -0,0 +1,29 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getClaimsHandler lists a theme's claims, optionally filtered by status,
+// e.g. ?status=pending for the review queue.
+func getClaimsHandler(c echo.Context) error {
+	theme, found := getThemeByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	status := c.QueryParam("status")
+
+	claims := []*Claim{}
+	for _, claim := range theme.Claims {
+		if status == "" || claim.Status == status {
+			claims = append(claims, claim)
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"claims": claims,
+	})
+}
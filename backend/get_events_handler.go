@@ -0,0 +1,70 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// itemEvents are the events that mark or unmark an item: admin toggles,
+// automated draws and player daubs. Their subject is the item.
+var itemEvents = []EventType{EventItemToggled, EventItemDrawn, EventCardDaubed}
+
+// getEventsHandler returns the newest events matching the optional filters,
+// e.g. ?item_id=...&since=2025-01-01T21:10:00Z&until=2025-01-01T21:20:00Z
+func getEventsHandler(c echo.Context) error {
+	var req struct {
+		Type      EventType `query:"type"`
+		ActorID   string    `query:"actor_id"`
+		ThemeID   string    `query:"theme_id"`
+		SubjectID string    `query:"subject_id"`
+		ItemID    string    `query:"item_id"`
+		Since     time.Time `query:"since"`
+		Until     time.Time `query:"until"`
+		Limit     int       `query:"limit"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	if req.ItemID != "" {
+		req.SubjectID = req.ItemID
+	}
+	if req.Limit <= 0 || req.Limit > 1000 {
+		req.Limit = 100
+	}
+
+	matches := []*Event{}
+	err := events.scan(func(e *Event) bool {
+		switch {
+		case req.Type != "" && e.Type != req.Type,
+			req.ActorID != "" && e.ActorID != req.ActorID,
+			req.ThemeID != "" && e.ThemeID != req.ThemeID,
+			req.SubjectID != "" && e.SubjectID != req.SubjectID,
+			req.ItemID != "" && !slices.Contains(itemEvents, e.Type),
+			!req.Since.IsZero() && e.At.Before(req.Since),
+			!req.Until.IsZero() && e.At.After(req.Until):
+			return true
+		}
+
+		matches = append(matches, e)
+		// Only the newest Limit matches are returned
+		if len(matches) > req.Limit {
+			matches = matches[1:]
+		}
+		return true
+	})
+	if err != nil {
+		c.Logger().Error("Error reading event log:", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to read event log"})
+	}
+
+	slices.Reverse(matches)
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"events": matches,
+	})
+}
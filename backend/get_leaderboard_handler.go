@@ -0,0 +1,16 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getLeaderboardHandler ranks players across every theme ever played
+// using the default scoring rules.
+func getLeaderboardHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]any{
+		"scoring":     defaultScoring,
+		"leaderboard": leaderboard(db.Themes, defaultScoring),
+	})
+}
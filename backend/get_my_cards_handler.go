@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getMyCardsHandler lists the current user's cards in a theme, or their
+// team's, along with how many more cards and rerolls they have left.
+func getMyCardsHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	theme, found := getThemeByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	holderID, err := theme.holderID(user)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	cards := theme.userCards(holderID)
+	if cards == nil {
+		cards = []*Card{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"cards":             cards,
+		"max_cards":         theme.maxCardsPerPlayer(),
+		"rerolls_remaining": max(theme.MaxRerolls-theme.rerolls(holderID), 0),
+		"locked":            theme.cardsLocked(),
+	})
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getNearWinsHandler returns the theme's current near-win summary, the
+// same data sent in near_wins broadcasts.
+func getNearWinsHandler(c echo.Context) error {
+	theme, found := getThemeByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	return c.JSON(http.StatusOK, theme.nearWins())
+}
@@ -0,0 +1,25 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getRoomHandler returns a room together with its active theme, if any.
+func getRoomHandler(c echo.Context) error {
+	room, ok := findRoom(c.Param("roomId"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Room not found"})
+	}
+
+	var activeTheme *Theme
+	if theme, ok := findTheme(db, room.ActiveThemeID); ok {
+		activeTheme = forPlayers(theme)
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"room":         room,
+		"active_theme": activeTheme,
+	})
+}
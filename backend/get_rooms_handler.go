@@ -0,0 +1,14 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getRoomsHandler lists every room, starting with the default one.
+func getRoomsHandler(c echo.Context) error {
+	return c.JSON(http.StatusOK, map[string]any{
+		"rooms": allRooms(),
+	})
+}
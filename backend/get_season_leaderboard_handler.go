@@ -0,0 +1,22 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getSeasonLeaderboardHandler ranks players across the season's themes
+// using the season's scoring rules.
+func getSeasonLeaderboardHandler(c echo.Context) error {
+	season, found := getSeasonByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Season not found"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"season":      season,
+		"scoring":     season.scoring(),
+		"leaderboard": leaderboard(season.themes(), season.scoring()),
+	})
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func getSeasonsHandler(c echo.Context) error {
+	seasons := db.Seasons
+	if seasons == nil {
+		seasons = []*Season{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"seasons": seasons,
+	})
+}
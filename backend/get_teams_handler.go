@@ -0,0 +1,34 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// getTeamsHandler lists a team theme's teams along with the current user's
+// team, if they have joined one.
+func getTeamsHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	theme, found := getThemeByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	teams := theme.Teams
+	if teams == nil {
+		teams = []*Team{}
+	}
+
+	var myTeamID string
+	if team, ok := theme.userTeam(user.ID); ok {
+		myTeamID = team.ID
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"teams":      teams,
+		"my_team_id": myTeamID,
+		"locked":     theme.cardsLocked(),
+	})
+}
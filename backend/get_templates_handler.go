@@ -0,0 +1,18 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func getTemplatesHandler(c echo.Context) error {
+	templates := db.Templates
+	if templates == nil {
+		templates = []*ThemeTemplate{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"templates": templates,
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	defaultSimulations = 1000
+	maxSimulations     = 20000
+)
+
+// getThemeAnalyticsHandler reports, for each item, how many cards hold it
+// and how many calling it would complete, and estimates each player's
+// chance of winning next. Pass seed to repeat an earlier estimate. It holds
+// dbMutex only while copying the theme.
+func getThemeAnalyticsHandler(c echo.Context) error {
+	var req struct {
+		ThemeID     string `param:"id"`
+		Simulations *int   `query:"simulations"`
+		Seed        uint64 `query:"seed"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	simulations := defaultSimulations
+	if req.Simulations != nil {
+		simulations = *req.Simulations
+	}
+	if simulations < 0 || simulations > maxSimulations {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Simulations must be between 0 and %d", maxSimulations)})
+	}
+
+	seed := Seed(cmp.Or(req.Seed, rand.Uint64()))
+
+	dbMutex.RLock()
+	theme, found := getThemeByID(req.ThemeID)
+	var an *analysis
+	if found {
+		an = theme.prepareAnalytics()
+	}
+	dbMutex.RUnlock()
+
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	return c.JSON(http.StatusOK, an.simulate(simulations, seed))
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+func getThemeWinnersHandler(c echo.Context) error {
+	theme, found := getThemeByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	usernames := make(map[string]string, len(db.Users))
+	for _, user := range db.Users {
+		usernames[user.ID] = user.Username
+	}
+
+	type rankedWinner struct {
+		RankingEntry
+		Username string `json:"username"`
+		TeamName string `json:"team_name,omitempty"`
+	}
+
+	ranking := []rankedWinner{}
+	for _, entry := range theme.ranking() {
+		winner := rankedWinner{RankingEntry: entry, Username: usernames[entry.UserID]}
+		if team, ok := theme.getTeam(entry.TeamID); ok {
+			winner.TeamName = team.Name
+		}
+		ranking = append(ranking, winner)
+	}
+
+	wins := theme.Wins
+	if wins == nil {
+		wins = []*Win{}
+	}
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"wins":    wins,
+		"ranking": ranking,
+	})
+}
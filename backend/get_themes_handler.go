@@ -2,13 +2,26 @@ package main
 
 import (
 	"net/http"
+	"slices"
 
 	"github.com/labstack/echo/v4"
 )
 
 func getThemesHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+	isAdmin := slices.Contains(db.AdminDiscordIDs, user.DiscordID)
+
+	themes := make([]*Theme, len(db.Themes))
+	for i, theme := range db.Themes {
+		themes[i] = forPlayers(theme)
+		// Admins can check cards against the seed while the game runs
+		if isAdmin {
+			themes[i].Seed = theme.Seed
+		}
+	}
+
 	return c.JSON(http.StatusOK, map[string]any{
-		"themes":          db.Themes,
+		"themes":          themes,
 		"active_theme_id": db.ActiveThemeID,
 	})
 }
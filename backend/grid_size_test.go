@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestDealCardsOfEachSize(t *testing.T) {
+	for _, size := range []int{3, 4, 7} {
+		theme := newTestTheme("t1", size, requiredItems(size))
+
+		card, err := theme.NewCard(&User{ID: "u1"})
+		if err != nil {
+			t.Fatalf("%dx%d: %v", size, size, err)
+		}
+		if len(card.Items) != size {
+			t.Fatalf("%dx%d: card has %d rows", size, size, len(card.Items))
+		}
+
+		seen := make(map[string]bool)
+		free := 0
+		for _, row := range card.Items {
+			if len(row) != size {
+				t.Fatalf("%dx%d: row %v has %d cells", size, size, row, len(row))
+			}
+			for _, id := range row {
+				if id == freeSpaceID {
+					free++
+				} else if seen[id] {
+					t.Errorf("%dx%d: item %s dealt twice", size, size, id)
+				}
+				seen[id] = true
+			}
+		}
+
+		row, col, hasFree := freeSpace(size)
+		switch {
+		case size%2 == 1 && (!hasFree || card.Items[row][col] != freeSpaceID || free != 1):
+			t.Errorf("%dx%d: want one free space in the center, got %d", size, size, free)
+		case size%2 == 0 && (hasFree || free != 0):
+			t.Errorf("%dx%d: want no free space, got %d", size, size, free)
+		}
+	}
+}
+
+func TestRequiredItems(t *testing.T) {
+	for size, want := range map[int]int{3: 8, 4: 16, 5: 24, 7: 48} {
+		if got := requiredItems(size); got != want {
+			t.Errorf("requiredItems(%d) = %d, want %d", size, got, want)
+		}
+	}
+
+	theme := newTestTheme("t1", 4, 15)
+	if _, err := theme.NewCard(&User{ID: "u1"}); err == nil {
+		t.Error("dealt a 4x4 card from 15 items")
+	}
+}
+
+func TestValidateGridSize(t *testing.T) {
+	for size := range 10 {
+		err := validateGridSize(size)
+		if valid := size >= minGridSize && size <= maxGridSize; (err == nil) != valid {
+			t.Errorf("validateGridSize(%d) = %v, want valid %t", size, err, valid)
+		}
+	}
+}
+
+func TestThemeHandlersRejectGridSizes(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "admin-discord"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	db.Themes = append(db.Themes, newTestTheme("t1", 5, 24))
+
+	server := newTestServer(t)
+	token := testToken(t, admin.ID)
+
+	items := make([]string, 64)
+	for i := range items {
+		items[i] = fmt.Sprintf(`"item %d"`, i)
+	}
+	itemList := "[" + strings.Join(items, ",") + "]"
+
+	for _, size := range []int{2, 8} {
+		body := fmt.Sprintf(`{"name": "Theme", "grid_size": %d, "items": %s}`, size, itemList)
+		if code, _ := doRequest(t, http.MethodPost, server.URL+"/api/admin/themes", token, body); code != http.StatusBadRequest {
+			t.Errorf("create with grid size %d = %d, want %d", size, code, http.StatusBadRequest)
+		}
+
+		body = fmt.Sprintf(`{"name": "Theme", "grid_size": %d, "items": []}`, size)
+		if code, _ := doRequest(t, http.MethodPut, server.URL+"/api/admin/themes/t1", token, body); code != http.StatusBadRequest {
+			t.Errorf("update to grid size %d = %d, want %d", size, code, http.StatusBadRequest)
+		}
+	}
+	if theme, _ := getThemeByID("t1"); theme.GridSize != 5 {
+		t.Errorf("grid size = %d after rejected updates, want 5", theme.GridSize)
+	}
+
+	body := fmt.Sprintf(`{"name": "Theme", "grid_size": 7, "items": %s}`, itemList)
+	if code, body := doRequest(t, http.MethodPost, server.URL+"/api/admin/themes", token, body); code != http.StatusCreated {
+		t.Errorf("create with grid size 7 = %d %s", code, body)
+	}
+}
+
+func TestMigrateDefaultGridSize(t *testing.T) {
+	d := &Database{
+		SchemaVersion: 2,
+		Themes:        []*Theme{{ID: "old"}, {ID: "small", GridSize: 3}},
+	}
+
+	if _, err := migrateDatabase(d); err != nil {
+		t.Fatal(err)
+	}
+	for id, want := range map[string]int{"old": defaultGridSize, "small": 3} {
+		if theme, _ := findTheme(d, id); theme.GridSize != want {
+			t.Errorf("theme %s has grid size %d, want %d", id, theme.GridSize, want)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+	"github.com/labstack/echo/v4"
+)
+
+// useDatabase installs d and s as the global database and store for the
+// rest of the test, restoring the previous globals when it ends. The event
+// log is disabled unless the test opens its own.
+func useDatabase(t *testing.T, d *Database, s Store) {
+	t.Helper()
+
+	oldDB, oldStore, oldEvents := db, store, events
+	t.Cleanup(func() {
+		db, store, events = oldDB, oldStore, oldEvents
+	})
+
+	db, store, events = d, s, nil
+}
+
+// useTestStore installs an empty database backed by a JSON store in a
+// temporary directory and returns the store.
+func useTestStore(t *testing.T) *jsonStore {
+	t.Helper()
+
+	s := newJSONStore(filepath.Join(t.TempDir(), "database.json"), 0)
+	useDatabase(t, newDatabase(), s)
+
+	return s
+}
+
+// newTestTheme returns an open theme with a gridSize grid and n items whose
+// IDs are "a", "b", "c" and so on.
+func newTestTheme(id string, gridSize, n int) *Theme {
+	theme := &Theme{
+		ID:       id,
+		Name:     id,
+		GridSize: gridSize,
+		Cards:    make(map[string]*Card),
+	}
+	theme.setState(ThemeOpen)
+
+	for i := range n {
+		itemID := string(rune('a' + i))
+		theme.Items = append(theme.Items, &Item{ID: itemID, Name: itemID})
+	}
+
+	return theme
+}
+
+// markItems marks the theme's items with the given IDs as called.
+func markItems(t *testing.T, theme *Theme, ids ...string) {
+	t.Helper()
+
+	for _, id := range ids {
+		item, ok := theme.GetItem(id)
+		if !ok {
+			t.Fatalf("item %q not found", id)
+		}
+		item.Marked = true
+	}
+}
+
+// newTestServer serves the application's routes over HTTP for the rest of
+// the test.
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	e := echo.New()
+	e.HideBanner = true
+	registerRoutes(e)
+
+	server := httptest.NewServer(e)
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+// testToken signs a session token for the user.
+func testToken(t *testing.T, userID string) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"user_id": userID,
+		"exp":     time.Now().Add(time.Hour).Unix(),
+	}).SignedString(jwtSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+// doRequest sends a request with an optional JSON body as the holder of
+// token and returns the status code and response body.
+func doRequest(t *testing.T, method, url, token, body string) (int, string) {
+	t.Helper()
+
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Error(err)
+		return 0, ""
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Error(err)
+	}
+
+	return resp.StatusCode, string(data)
+}
+
+// waitForClients waits until n WebSocket clients are registered.
+func waitForClients(t *testing.T, n int) {
+	t.Helper()
+
+	for deadline := time.Now().Add(5 * time.Second); ; {
+		connMutex.RLock()
+		registered := len(connections)
+		connMutex.RUnlock()
+		if registered == n {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d clients registered, want %d", registered, n)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
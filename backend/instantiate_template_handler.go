@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/labstack/echo/v4"
+)
+
+// instantiateTemplateHandler creates a draft theme from a template. Any
+// theme field in the body, such as name, items or grid_size, overrides the
+// template's value; state may be set to open to skip the draft.
+func instantiateTemplateHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	template, found := getTemplateByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Template not found"})
+	}
+
+	settings := template.ThemeSettings
+	settings.WinPatterns = slices.Clone(settings.WinPatterns)
+	settings.LayoutRules = slices.Clone(settings.LayoutRules)
+
+	fields := themeFields{
+		Name:          template.Name,
+		Description:   template.Description,
+		Items:         slices.Clone(template.Items),
+		ThemeSettings: settings,
+		State:         ThemeDraft,
+	}
+
+	var req themeOverrides
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	req.apply(&fields)
+
+	theme, err := newTheme(fields.Name, fields.Description, fields.Items, fields.ThemeSettings, fields.State)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	addTheme(theme, user)
+
+	return c.JSON(http.StatusCreated, theme)
+}
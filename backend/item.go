@@ -1,7 +1,36 @@
 package main
 
+import "encoding/json"
+
 type Item struct {
-	ID     string `json:"id"`
-	Name   string `json:"name"`
-	Marked bool   `json:"marked"`
+	ID       string  `json:"id"`
+	Name     string  `json:"name"`
+	Marked   bool    `json:"marked"`
+	Weight   float64 `json:"weight,omitempty"`
+	Category string  `json:"category,omitempty"`
+}
+
+// weight returns how likely the item is to be dealt relative to the others.
+// Items without a weight count as 1.
+func (i *Item) weight() float64 {
+	if i.Weight == 0 {
+		return 1
+	}
+	return i.Weight
+}
+
+// itemSpec is an item as sent when creating a theme: either just its name
+// or an object that also sets its weight and category.
+type itemSpec struct {
+	Name     string  `json:"name"`
+	Weight   float64 `json:"weight"`
+	Category string  `json:"category"`
+}
+
+func (s *itemSpec) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		return json.Unmarshal(data, &s.Name)
+	}
+	type plain itemSpec
+	return json.Unmarshal(data, (*plain)(s))
 }
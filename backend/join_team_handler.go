@@ -0,0 +1,41 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// joinTeamHandler moves the current user onto a team, leaving the team
+// they were on before. Teams are fixed once the game starts.
+func joinTeamHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID string `param:"id"`
+		TeamID  string `param:"teamId"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	team, found := theme.getTeam(req.TeamID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Team not found"})
+	}
+
+	before := eventState(theme)
+	if err := theme.joinTeam(team, user); err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	saveTeams(theme, user, before)
+
+	return c.JSON(http.StatusOK, team)
+}
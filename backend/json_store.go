@@ -0,0 +1,257 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"runtime"
+	"time"
+)
+
+// jsonStore keeps the whole Database in a single JSON document. Every save
+// rewrites the file, so it is best suited to small deployments.
+//
+// Writes go to a temporary file that is fsynced and renamed over the main
+// file, so a crash never leaves a truncated document behind. The previous
+// documents are kept as path.1 (newest) through path.N.
+type jsonStore struct {
+	path        string
+	generations int
+	db          *Database
+
+	// While a Batch runs, saves only mark the document dirty
+	batching bool
+	dirty    bool
+}
+
+func newJSONStore(path string, generations int) *jsonStore {
+	return &jsonStore{path: path, generations: generations}
+}
+
+func (s *jsonStore) Load() (*Database, error) {
+	db, generation, err := s.read()
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Println("Database file not found, creating new one")
+		s.db = newDatabase()
+		return s.db, s.write()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	s.db = db
+	if generation == 0 {
+		return s.db, nil
+	}
+
+	log.Printf("Recovered database from generation %s", s.generationPath(generation))
+	// Keep the unreadable file around for inspection instead of rotating it into the generations
+	corruptPath := fmt.Sprintf("%s.corrupt-%s", s.path, time.Now().Format("20060102-150405"))
+	if err := os.Rename(s.path, corruptPath); err == nil {
+		log.Printf("Moved unreadable database to %s", corruptPath)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return s.db, s.write()
+}
+
+// read returns the main document, or the newest generation that still
+// parses along with its number, without changing any file. The error wraps
+// fs.ErrNotExist when there is no document at all.
+func (s *jsonStore) read() (*Database, int, error) {
+	db, err := readDatabaseFile(s.path)
+	if err == nil {
+		return db, 0, nil
+	}
+
+	mainMissing := errors.Is(err, fs.ErrNotExist)
+	if !mainMissing {
+		log.Printf("Error reading database %s: %v", s.path, err)
+	}
+
+	// Fall back to the newest generation that still parses
+	for i := 1; i <= s.generations; i++ {
+		path := s.generationPath(i)
+		db, genErr := readDatabaseFile(path)
+		if errors.Is(genErr, fs.ErrNotExist) {
+			continue
+		}
+		if genErr != nil {
+			log.Printf("Error reading database generation %s: %v", path, genErr)
+			continue
+		}
+		return db, i, nil
+	}
+
+	if !mainMissing {
+		return nil, 0, fmt.Errorf("no readable database or generation found: %w", err)
+	}
+	return nil, 0, err
+}
+
+func readDatabaseFile(path string) (*Database, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	db := newDatabase()
+	if err := json.Unmarshal(data, db); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+func (s *jsonStore) generationPath(i int) string {
+	return fmt.Sprintf("%s.%d", s.path, i)
+}
+
+// Batch runs fn and writes the document once if any save happened in it.
+func (s *jsonStore) Batch(fn func() error) error {
+	s.batching, s.dirty = true, false
+	err := fn()
+	s.batching = false
+	if err != nil || !s.dirty {
+		return err
+	}
+
+	return s.write()
+}
+
+func (s *jsonStore) write() error {
+	if s.batching {
+		s.dirty = true
+		return nil
+	}
+
+	data, err := json.MarshalIndent(s.db, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomic(s.path, data, s.rotate)
+}
+
+// writeFileAtomic writes data to a temporary file next to path, fsyncs it
+// and renames it into place. beforeRename, if set, runs just before the
+// rename.
+func writeFileAtomic(path string, data []byte, beforeRename func() error) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+
+	if beforeRename != nil {
+		if err := beforeRename(); err != nil {
+			return err
+		}
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	return syncDir(dir)
+}
+
+// rotate shifts path.1..path.N-1 up by one and links the current file as
+// path.1, dropping the oldest generation. The current file stays in place
+// until the new document is renamed over it, so path always exists.
+func (s *jsonStore) rotate() error {
+	if s.generations <= 0 {
+		return nil
+	}
+
+	for i := s.generations - 1; i >= 1; i-- {
+		if err := os.Rename(s.generationPath(i), s.generationPath(i+1)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+	}
+
+	newest := s.generationPath(1)
+	if err := os.Remove(newest); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	err := os.Link(s.path, newest)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		// Some filesystems do not support hard links
+		return copyFile(s.path, newest)
+	}
+
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	data, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(dst, data, 0644)
+}
+
+// syncDir flushes directory entries so completed renames survive a crash.
+// Windows does not support syncing directories, so it is skipped there.
+func syncDir(dir string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	return d.Sync()
+}
+
+func (s *jsonStore) Replace(db *Database) error {
+	s.db = db
+	return s.write()
+}
+
+func (s *jsonStore) SaveUser(*User) error               { return s.write() }
+func (s *jsonStore) SaveTheme(*Theme) error             { return s.write() }
+func (s *jsonStore) DeleteTheme(string) error           { return s.write() }
+func (s *jsonStore) SaveItem(string, *Item) error       { return s.write() }
+func (s *jsonStore) SaveCard(*Card) error               { return s.write() }
+func (s *jsonStore) SaveAdminDiscordIDs([]string) error { return s.write() }
+func (s *jsonStore) SaveActiveThemeID(string) error     { return s.write() }
+func (s *jsonStore) SaveRoom(*Room) error               { return s.write() }
+func (s *jsonStore) DeleteRoom(string) error            { return s.write() }
+func (s *jsonStore) SaveSeason(*Season) error           { return s.write() }
+func (s *jsonStore) DeleteSeason(string) error          { return s.write() }
+func (s *jsonStore) SaveTemplate(*ThemeTemplate) error  { return s.write() }
+func (s *jsonStore) DeleteTemplate(string) error        { return s.write() }
+func (s *jsonStore) Close() error                       { return nil }
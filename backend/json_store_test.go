@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// saveActiveTheme sets the active theme and writes the document.
+func saveActiveTheme(t *testing.T, s *jsonStore, d *Database, themeID string) {
+	t.Helper()
+
+	d.ActiveThemeID = themeID
+	if err := s.SaveActiveThemeID(themeID); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestJSONStoreKeepsGenerations(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "database.json")
+	s := newJSONStore(path, 2)
+
+	d, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		saveActiveTheme(t, s, d, id)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d files, want the database and 2 generations", len(entries))
+	}
+
+	for path, want := range map[string]string{path: "d", path + ".1": "c", path + ".2": "b"} {
+		got, err := readDatabaseFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.ActiveThemeID != want {
+			t.Errorf("%s has active theme %q, want %q", filepath.Base(path), got.ActiveThemeID, want)
+		}
+	}
+}
+
+func TestJSONStoreRotateKeepsMainFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "database.json")
+	s := newJSONStore(path, 1)
+
+	d, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveActiveTheme(t, s, d, "a")
+	saveActiveTheme(t, s, d, "b")
+
+	// rotate runs just before the new document is renamed into place
+	if err := s.rotate(); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readDatabaseFile(path)
+	if err != nil {
+		t.Fatalf("database missing between rotation and rename: %v", err)
+	}
+	if got.ActiveThemeID != "b" {
+		t.Errorf("database has active theme %q, want %q", got.ActiveThemeID, "b")
+	}
+	if gen, err := readDatabaseFile(path + ".1"); err != nil || gen.ActiveThemeID != "b" {
+		t.Errorf("generation 1 = %v, %v; want a copy of the current database", gen, err)
+	}
+}
+
+func TestJSONStoreRecoversFromCorruptFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "database.json")
+	s := newJSONStore(path, 2)
+
+	d, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	saveActiveTheme(t, s, d, "a")
+	saveActiveTheme(t, s, d, "b")
+
+	if err := os.WriteFile(path, []byte("{garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err = newJSONStore(path, 2).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ActiveThemeID != "a" {
+		t.Errorf("recovered active theme %q, want %q from generation 1", d.ActiveThemeID, "a")
+	}
+
+	corrupt, err := filepath.Glob(filepath.Join(dir, "database.json.corrupt-*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corrupt) != 1 {
+		t.Fatalf("got %d corrupt files, want 1", len(corrupt))
+	}
+	data, err := os.ReadFile(corrupt[0])
+	if err != nil || !strings.HasPrefix(string(data), "{garbage") {
+		t.Errorf("unreadable database not kept for inspection")
+	}
+}
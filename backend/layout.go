@@ -0,0 +1,184 @@
+package main
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"strings"
+)
+
+// Layout rule types
+const (
+	// LayoutColumn fills column Index only with items of Category
+	LayoutColumn = "column"
+	// LayoutRow fills row Index only with items of Category
+	LayoutRow = "row"
+	// LayoutMaxPerRow allows at most Max items of Category in any row
+	LayoutMaxPerRow = "max_per_row"
+	// LayoutMaxPerColumn allows at most Max items of Category in any column
+	LayoutMaxPerColumn = "max_per_column"
+)
+
+// LayoutRule constrains where items of a category may be placed on a card.
+type LayoutRule struct {
+	Type     string `json:"type"`
+	Category string `json:"category"`
+	Index    int    `json:"index,omitempty"`
+	Max      int    `json:"max,omitempty"`
+}
+
+// validateLayoutRules checks that every rule has a known type and fits a
+// grid of the given size.
+func validateLayoutRules(rules []LayoutRule, size int) error {
+	for _, rule := range rules {
+		if rule.Category == "" {
+			return fmt.Errorf("%s layout rule needs a category", rule.Type)
+		}
+
+		switch rule.Type {
+		case LayoutColumn, LayoutRow:
+			if rule.Index < 0 || rule.Index >= size {
+				return fmt.Errorf("%s layout rule index must be between 0 and %d", rule.Type, size-1)
+			}
+		case LayoutMaxPerRow, LayoutMaxPerColumn:
+			if rule.Max < 0 {
+				return fmt.Errorf("%s layout rule max cannot be negative", rule.Type)
+			}
+		default:
+			return fmt.Errorf("unknown layout rule type %q", rule.Type)
+		}
+	}
+	return nil
+}
+
+// validateItemWeights rejects negative weights.
+func validateItemWeights(items []*Item) error {
+	for _, item := range items {
+		if item.Weight < 0 {
+			return fmt.Errorf("item %q has a negative weight", item.Name)
+		}
+	}
+	return nil
+}
+
+// usesLayout reports whether cards need the weighted, rule-aware
+// generator. Themes without weights or rules keep the plain shuffle so
+// their cards stay verifiable.
+func (t *Theme) usesLayout() bool {
+	if len(t.LayoutRules) > 0 {
+		return true
+	}
+	for _, item := range t.Items {
+		if item.weight() != 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// cellCategories returns the categories a cell is restricted to.
+func (t *Theme) cellCategories(row, col int) []string {
+	var categories []string
+	for _, rule := range t.LayoutRules {
+		if rule.Type == LayoutColumn && rule.Index == col || rule.Type == LayoutRow && rule.Index == row {
+			categories = append(categories, rule.Category)
+		}
+	}
+	return categories
+}
+
+// fitsCell reports whether item may be placed in a cell given the
+// category counts already in its row and column.
+func (t *Theme) fitsCell(item *Item, categories []string, rowCounts, colCounts map[string]int) bool {
+	for _, category := range categories {
+		if item.Category != category {
+			return false
+		}
+	}
+
+	for _, rule := range t.LayoutRules {
+		if rule.Category != item.Category {
+			continue
+		}
+		if rule.Type == LayoutMaxPerRow && rowCounts[item.Category] >= rule.Max {
+			return false
+		}
+		if rule.Type == LayoutMaxPerColumn && colCounts[item.Category] >= rule.Max {
+			return false
+		}
+	}
+
+	return true
+}
+
+// layoutGrid deals a grid by weighted sampling without replacement,
+// filling restricted cells first. It reports false when the rules leave a
+// cell with no eligible item.
+func (t *Theme) layoutGrid(src rand.Source, size int) ([][]string, bool) {
+	pool := slices.Clone(t.Items)
+	slices.SortFunc(pool, func(a, b *Item) int {
+		return strings.Compare(a.ID, b.ID)
+	})
+
+	type cell struct {
+		row, col   int
+		categories []string
+	}
+
+	freeRow, freeCol, hasFree := freeSpace(size)
+	grid := make([][]string, size)
+	var cells []cell
+	for row := range size {
+		grid[row] = make([]string, size)
+		for col := range size {
+			if hasFree && row == freeRow && col == freeCol {
+				grid[row][col] = freeSpaceID
+				continue
+			}
+			cells = append(cells, cell{row, col, t.cellCategories(row, col)})
+		}
+	}
+	slices.SortStableFunc(cells, func(a, b cell) int {
+		return len(b.categories) - len(a.categories)
+	})
+
+	used := make(map[string]bool)
+	rowCounts := make([]map[string]int, size)
+	colCounts := make([]map[string]int, size)
+	for i := range size {
+		rowCounts[i] = make(map[string]int)
+		colCounts[i] = make(map[string]int)
+	}
+
+	for _, cell := range cells {
+		var candidates []*Item
+		var total float64
+		for _, item := range pool {
+			if !used[item.ID] && t.fitsCell(item, cell.categories, rowCounts[cell.row], colCounts[cell.col]) {
+				candidates = append(candidates, item)
+				total += item.weight()
+			}
+		}
+		if len(candidates) == 0 {
+			return nil, false
+		}
+
+		// 53 random bits give a uniform float in [0, 1)
+		pick := float64(src.Uint64()>>11) / (1 << 53) * total
+		chosen := candidates[len(candidates)-1]
+		for _, candidate := range candidates {
+			pick -= candidate.weight()
+			if pick < 0 {
+				chosen = candidate
+				break
+			}
+		}
+
+		used[chosen.ID] = true
+		rowCounts[cell.row][chosen.Category]++
+		colCounts[cell.col][chosen.Category]++
+		grid[cell.row][cell.col] = chosen.ID
+	}
+
+	return grid, true
+}
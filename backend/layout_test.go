@@ -0,0 +1,112 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+// newLayoutTheme returns an open 5x5 theme with ten "rare" items, twelve
+// "easy" items and twelve uncategorised ones.
+func newLayoutTheme(rules ...LayoutRule) *Theme {
+	theme := newTestTheme("t1", 5, 0)
+	theme.Seed = 9
+	theme.LayoutRules = rules
+
+	for i := range 10 {
+		theme.Items = append(theme.Items, &Item{ID: fmt.Sprint("r", i), Category: "rare", Weight: 0.5})
+	}
+	for i := range 12 {
+		theme.Items = append(theme.Items, &Item{ID: fmt.Sprint("e", i), Category: "easy"})
+	}
+	for i := range 12 {
+		theme.Items = append(theme.Items, &Item{ID: fmt.Sprint("m", i)})
+	}
+
+	return theme
+}
+
+func TestLayoutRulesAreFollowed(t *testing.T) {
+	theme := newLayoutTheme(
+		LayoutRule{Type: LayoutColumn, Category: "rare", Index: 0},
+		LayoutRule{Type: LayoutMaxPerRow, Category: "easy", Max: 2},
+	)
+	categories := make(map[string]string, len(theme.Items))
+	for _, item := range theme.Items {
+		categories[item.ID] = item.Category
+	}
+
+	for i := range 5 {
+		card, err := theme.NewCard(&User{ID: fmt.Sprint("u", i)})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, row := range card.Items {
+			if categories[row[0]] != "rare" {
+				t.Errorf("card %s: first column holds %s, want a rare item", card.ID, row[0])
+			}
+			easy := 0
+			for _, id := range row {
+				if categories[id] == "easy" {
+					easy++
+				}
+			}
+			if easy > 2 {
+				t.Errorf("card %s: row %v has %d easy items, want at most 2", card.ID, row, easy)
+			}
+		}
+
+		if v := theme.verifyCard(card); !v.Matches {
+			t.Errorf("card %s does not verify: %s", card.ID, v.Reason)
+		}
+	}
+}
+
+func TestImpossibleLayoutRules(t *testing.T) {
+	// The easy items cannot fill row 0 when the rare items hold column 0
+	theme := newLayoutTheme(
+		LayoutRule{Type: LayoutColumn, Category: "rare", Index: 0},
+		LayoutRule{Type: LayoutRow, Category: "easy", Index: 0},
+	)
+
+	if _, err := theme.NewCard(&User{ID: "u1"}); err == nil {
+		t.Error("dealt a card that breaks the layout rules")
+	}
+}
+
+func TestValidateLayoutRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  LayoutRule
+		valid bool
+	}{
+		{"column", LayoutRule{Type: LayoutColumn, Category: "rare", Index: 4}, true},
+		{"max per row", LayoutRule{Type: LayoutMaxPerRow, Category: "easy", Max: 2}, true},
+		{"no category", LayoutRule{Type: LayoutColumn, Index: 0}, false},
+		{"index off the grid", LayoutRule{Type: LayoutRow, Category: "rare", Index: 5}, false},
+		{"negative max", LayoutRule{Type: LayoutMaxPerColumn, Category: "easy", Max: -1}, false},
+		{"unknown type", LayoutRule{Type: "diagonal", Category: "rare"}, false},
+	}
+
+	for _, tt := range tests {
+		err := validateLayoutRules([]LayoutRule{tt.rule}, 5)
+		if (err == nil) != tt.valid {
+			t.Errorf("%s: validateLayoutRules = %v, want valid %t", tt.name, err, tt.valid)
+		}
+	}
+}
+
+func TestItemSpecJSON(t *testing.T) {
+	var specs []itemSpec
+	if err := json.Unmarshal([]byte(`["a", {"name": "b", "weight": 2, "category": "rare"}]`), &specs); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(specs) != 2 || specs[0].Name != "a" {
+		t.Fatalf("specs = %+v, want a plain item named a first", specs)
+	}
+	if specs[1].Name != "b" || specs[1].Weight != 2 || specs[1].Category != "rare" {
+		t.Errorf("spec = %+v, want b weighted 2 in rare", specs[1])
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// leaveTeamHandler takes the current user off their team before the game
+// starts. The team keeps its cards.
+func leaveTeamHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	theme, found := getThemeByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	if err := theme.checkTeamChange(); err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	before := eventState(theme)
+	if _, ok := theme.leaveTeam(user); !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "You are not on a team"})
+	}
+
+	saveTeams(theme, user, before)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Left team"})
+}
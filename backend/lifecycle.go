@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+)
+
+// Theme states
+const (
+	ThemeDraft    = "draft"    // Being set up, hidden from card generation
+	ThemeOpen     = "open"     // Players can get, add and reroll cards
+	ThemeLocked   = "locked"   // Cards are final, the game has not started
+	ThemeLive     = "live"     // Items are being called
+	ThemeFinished = "finished" // The game is over
+	ThemeArchived = "archived" // Kept for history only
+)
+
+// themeTransitions lists the states each state may move to.
+var themeTransitions = map[string][]string{
+	ThemeDraft:    {ThemeOpen, ThemeArchived},
+	ThemeOpen:     {ThemeDraft, ThemeLocked, ThemeLive, ThemeFinished},
+	ThemeLocked:   {ThemeOpen, ThemeLive, ThemeFinished},
+	ThemeLive:     {ThemeFinished},
+	ThemeFinished: {ThemeLive, ThemeArchived},
+	ThemeArchived: {},
+}
+
+// canTransition reports whether the theme may move to state.
+func (t *Theme) canTransition(state string) bool {
+	return slices.Contains(themeTransitions[t.State], state)
+}
+
+// setState moves the theme to state, keeping IsComplete in step for
+// clients that only know about completion.
+func (t *Theme) setState(state string) {
+	t.State = state
+	t.IsComplete = state == ThemeFinished || state == ThemeArchived
+}
+
+// changeThemeState validates and applies a transition, then saves, records
+// and broadcasts it. A theme that finishes stops being active in its room.
+// Callers hold dbMutex.
+func changeThemeState(t *Theme, actor *User, state string) error {
+	if !t.canTransition(state) {
+		return fmt.Errorf("cannot move theme from %s to %s", t.State, state)
+	}
+
+	before := eventState(t)
+	t.setState(state)
+
+	if room, ok := themeRoom(t.ID); ok && t.IsComplete {
+		setRoomTheme(room, actor, "")
+	}
+
+	if err := store.SaveTheme(t); err != nil {
+		log.Println("Error saving database:", err)
+	}
+	recordEvent(EventThemeUpdated, actor, t.ID, t.ID, before, t)
+
+	broadcastUpdate("theme_updated", forPlayers(t))
+
+	return nil
+}
+
+// startPlay makes sure items can be called in the theme, moving an open or
+// locked theme live when the first item is called. Callers hold dbMutex.
+func startPlay(t *Theme, actor *User) error {
+	switch t.State {
+	case ThemeLive:
+		return nil
+	case ThemeOpen, ThemeLocked:
+		return changeThemeState(t, actor, ThemeLive)
+	}
+	return fmt.Errorf("items cannot be called while the theme is %s", t.State)
+}
+
+// scheduledState returns the state the theme's schedule moves it to at
+// now. Starting takes an open or locked theme live; ending finishes a live
+// one.
+func (t *Theme) scheduledState(now time.Time) (string, bool) {
+	if t.StartsAt != nil && !now.Before(*t.StartsAt) && (t.State == ThemeOpen || t.State == ThemeLocked) {
+		return ThemeLive, true
+	}
+	if t.EndsAt != nil && !now.Before(*t.EndsAt) && t.State == ThemeLive {
+		return ThemeFinished, true
+	}
+	return "", false
+}
+
+// runThemeSchedule applies scheduled starts and ends as they fall due,
+// until ctx is cancelled.
+func runThemeSchedule(ctx context.Context) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			dbMutex.Lock()
+			for _, theme := range db.Themes {
+				state, ok := theme.scheduledState(now)
+				if !ok {
+					continue
+				}
+
+				// Each scheduled time fires once
+				if state == ThemeLive {
+					theme.StartsAt = nil
+				} else {
+					theme.EndsAt = nil
+				}
+
+				if err := changeThemeState(theme, nil, state); err != nil {
+					log.Println("Error applying theme schedule:", err)
+					continue
+				}
+				log.Printf("Theme %s is now %s", theme.ID, state)
+			}
+			dbMutex.Unlock()
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestChangeThemeState(t *testing.T) {
+	useTestStore(t)
+
+	theme := newTestTheme("t1", 3, 9)
+	theme.setState(ThemeDraft)
+	db.Themes = append(db.Themes, theme)
+
+	if err := changeThemeState(theme, nil, ThemeLive); err == nil {
+		t.Fatal("draft theme went straight to live")
+	}
+	for _, state := range []string{ThemeOpen, ThemeLocked, ThemeLive} {
+		if err := changeThemeState(theme, nil, state); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Finishing the active theme clears it from its room
+	db.ActiveThemeID = theme.ID
+	if err := changeThemeState(theme, nil, ThemeFinished); err != nil {
+		t.Fatal(err)
+	}
+	if !theme.IsComplete {
+		t.Error("finished theme is not complete")
+	}
+	if db.ActiveThemeID != "" {
+		t.Errorf("active theme = %q after it finished, want none", db.ActiveThemeID)
+	}
+}
+
+func TestScheduledState(t *testing.T) {
+	now := time.Now()
+	past := now.Add(-time.Second)
+
+	theme := newTestTheme("t1", 3, 9)
+	theme.setState(ThemeLocked)
+	theme.StartsAt, theme.EndsAt = &past, &past
+
+	if state, ok := theme.scheduledState(now); !ok || state != ThemeLive {
+		t.Errorf("scheduled state of a locked theme past its start = %q, want %s", state, ThemeLive)
+	}
+
+	theme.setState(ThemeLive)
+	if state, ok := theme.scheduledState(now); !ok || state != ThemeFinished {
+		t.Errorf("scheduled state of a live theme past its end = %q, want %s", state, ThemeFinished)
+	}
+
+	theme.setState(ThemeFinished)
+	if state, ok := theme.scheduledState(now); ok {
+		t.Errorf("finished theme is scheduled to move to %s", state)
+	}
+}
+
+func TestCallingItemsFollowsState(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "admin-discord"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	draft := newTestTheme("draft", 3, 9)
+	draft.setState(ThemeDraft)
+	open := newTestTheme("open", 3, 9)
+	db.Themes = append(db.Themes, draft, open)
+
+	server := newTestServer(t)
+	token := testToken(t, admin.ID)
+	toggle := func(themeID string) int {
+		code, _ := doRequest(t, http.MethodPost, server.URL+"/api/admin/themes/"+themeID+"/items/a/toggle", token, "")
+		return code
+	}
+
+	if code := toggle("draft"); code != http.StatusConflict {
+		t.Errorf("calling an item in a draft theme = %d, want %d", code, http.StatusConflict)
+	}
+	if item, _ := draft.GetItem("a"); item.Marked {
+		t.Error("item was marked in a draft theme")
+	}
+	code, _ := doRequest(t, http.MethodPost, server.URL+"/api/admin/themes/draft/caller/start", token, "")
+	if code != http.StatusConflict || draft.Caller != nil {
+		t.Errorf("starting the caller of a draft theme = %d, want %d", code, http.StatusConflict)
+	}
+
+	// The first call starts the game
+	if code := toggle("open"); code != http.StatusOK {
+		t.Fatalf("calling an item in an open theme = %d", code)
+	}
+	if open.State != ThemeLive {
+		t.Errorf("theme is %s after its first call, want %s", open.State, ThemeLive)
+	}
+
+	open.setState(ThemeFinished)
+	if code := toggle("open"); code != http.StatusConflict {
+		t.Errorf("calling an item in a finished theme = %d, want %d", code, http.StatusConflict)
+	}
+}
+
+func TestDaubingNeedsLiveTheme(t *testing.T) {
+	useTestStore(t)
+
+	user := &User{ID: "u1"}
+	db.Users = append(db.Users, user)
+	theme := newTestTheme("t1", 3, 9)
+	theme.MarkingMode = MarkingDaub
+	db.Themes = append(db.Themes, theme)
+
+	card, err := theme.NewCard(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	itemID := card.Items[0][0]
+	markItems(t, theme, itemID)
+	theme.setState(ThemeFinished)
+
+	server := newTestServer(t)
+	url := server.URL + "/api/themes/t1/items/" + itemID + "/daub"
+	if code, _ := doRequest(t, http.MethodPost, url, testToken(t, user.ID), ""); code != http.StatusConflict {
+		t.Errorf("daubing in a finished theme = %d, want %d", code, http.StatusConflict)
+	}
+	if len(card.Daubed) != 0 {
+		t.Errorf("card was daubed in a finished theme: %v", card.Daubed)
+	}
+}
+
+func TestCheckRoomTheme(t *testing.T) {
+	useTestStore(t)
+
+	draft := newTestTheme("draft", 3, 9)
+	draft.setState(ThemeDraft)
+	finished := newTestTheme("finished", 3, 9)
+	finished.setState(ThemeFinished)
+	open := newTestTheme("open", 3, 9)
+	db.Themes = append(db.Themes, draft, finished, open)
+
+	room := &Room{ID: "r1", Name: "Room"}
+	for _, id := range []string{"draft", "finished", "missing"} {
+		if err := checkRoomTheme(room, id); err == nil {
+			t.Errorf("theme %s can be set as active", id)
+		}
+	}
+	for _, id := range []string{"open", ""} {
+		if err := checkRoomTheme(room, id); err != nil {
+			t.Errorf("theme %q cannot be set as active: %v", id, err)
+		}
+	}
+}
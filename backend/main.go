@@ -2,20 +2,71 @@ package main
 
 import (
 	"cmp"
+	"context"
+	"errors"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 )
 
 func main() {
+	migrateDryRun := flag.Bool("migrate-dry-run", false, "report pending database migrations and exit")
+	rebuildPath := flag.String("rebuild-from-events", "", "replay the event log into a database file at this path and exit")
+	flag.Parse()
+
+	eventLogPath := cmp.Or(os.Getenv("EVENT_LOG_PATH"), "data/events.jsonl")
+
+	if *rebuildPath != "" {
+		if err := rebuildFromEvents(eventLogPath, *rebuildPath); err != nil {
+			log.Fatal("Error rebuilding from events:", err)
+		}
+		log.Printf("Rebuilt database from %s into %s", eventLogPath, *rebuildPath)
+		return
+	}
+
+	if *migrateDryRun {
+		if err := dryRunMigrations(); err != nil {
+			log.Fatal("Error checking migrations:", err)
+		}
+		return
+	}
+
+	shutdownTimeout, err := time.ParseDuration(cmp.Or(os.Getenv("SHUTDOWN_TIMEOUT"), "10s"))
+	if err != nil {
+		log.Fatal("Invalid SHUTDOWN_TIMEOUT:", err)
+	}
+
 	if err := loadDatabase(); err != nil {
 		log.Fatal("Error loading database:", err)
 	}
 
+	if events, err = openEventLog(eventLogPath); err != nil {
+		log.Fatal("Error opening event log:", err)
+	}
+	if events.empty() {
+		// Give replay a starting point for data that predates the log
+		recordEvent(EventSnapshot, nil, "", "", nil, db)
+	}
+
+	backupInterval, err := time.ParseDuration(cmp.Or(os.Getenv("BACKUP_INTERVAL"), "0"))
+	if err != nil {
+		log.Fatal("Invalid BACKUP_INTERVAL:", err)
+	}
+	backupRetention, err := strconv.Atoi(cmp.Or(os.Getenv("BACKUP_RETENTION"), "7"))
+	if err != nil {
+		log.Fatal("Invalid BACKUP_RETENTION:", err)
+	}
+
 	fmt.Printf("discord client id: %s\n", discordOAuth.ClientID)
 
 	e := echo.New()
@@ -29,31 +80,151 @@ func main() {
 		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
 	}))
 
-	// Routes
+	registerRoutes(e)
+
+	port := cmp.Or(os.Getenv("PORT"), "8080")
+	log.Printf("Server starting on port %s", port)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Background jobs write to the store, so they must stop before it closes
+	var background sync.WaitGroup
+	runInBackground := func(job func(context.Context)) {
+		background.Add(1)
+		go func() {
+			defer background.Done()
+			job(ctx)
+		}()
+	}
+
+	// Draws items for themes in caller mode, resuming any interrupted game
+	runInBackground(runCaller)
+	runInBackground(runThemeSchedule)
+	if backupInterval > 0 {
+		runInBackground(func(ctx context.Context) {
+			runBackupSchedule(ctx, backupInterval, backupRetention)
+		})
+	}
+
+	go func() {
+		if err := e.Start(":" + port); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			e.Logger.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("Shutting down, waiting up to %s", shutdownTimeout)
 
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+
+	// Stop accepting connections and wait for in-flight requests
+	if err := e.Shutdown(shutdownCtx); err != nil {
+		log.Println("Error shutting down server:", err)
+	}
+
+	// WebSockets are hijacked and not tracked by the HTTP server
+	deadline, _ := shutdownCtx.Deadline()
+	closeAllConnections("server restarting", deadline)
+
+	// Write any batched saves before exiting
+	closed := make(chan error, 1)
+	go func() {
+		background.Wait()
+		closed <- errors.Join(store.Close(), events.Close())
+	}()
+
+	select {
+	case err := <-closed:
+		if err != nil {
+			log.Println("Error closing database:", err)
+			return
+		}
+		log.Println("Shutdown complete")
+	case <-shutdownCtx.Done():
+		log.Println("Timed out flushing database")
+	}
+}
+
+// registerRoutes adds the API, WebSocket and static file routes to e.
+func registerRoutes(e *echo.Echo) {
 	e.GET("/auth/discord", handleDiscordAuth)
 	e.POST("/auth/discord/exchange", authCodeExchangeHandler)
 
 	apiRoutes := e.Group("/api")
-	apiRoutes.GET("/user", getCurrentUser, authMiddleware)
-	apiRoutes.GET("/users", getAllUsersHandler, authMiddleware)
-	apiRoutes.GET("/themes", getThemesHandler, authMiddleware)
-	apiRoutes.GET("/themes/:id/items", getThemeItemsHandler, authMiddleware)
-	apiRoutes.GET("/themes/:id/cards/mine", getCardByUserIdHandler, authMiddleware)
+	apiRoutes.GET("/user", getCurrentUser, authMiddleware, readLock)
+	apiRoutes.GET("/users", getAllUsersHandler, authMiddleware, readLock)
+	apiRoutes.GET("/rooms", getRoomsHandler, authMiddleware, readLock)
+	apiRoutes.GET("/rooms/:roomId", getRoomHandler, authMiddleware, readLock)
+	apiRoutes.GET("/seasons", getSeasonsHandler, authMiddleware, readLock)
+	apiRoutes.GET("/seasons/:id/leaderboard", getSeasonLeaderboardHandler, authMiddleware, readLock)
+	apiRoutes.GET("/leaderboard", getLeaderboardHandler, authMiddleware, readLock)
+	apiRoutes.GET("/themes", getThemesHandler, authMiddleware, readLock)
+	apiRoutes.GET("/themes/:id/items", getThemeItemsHandler, authMiddleware, readLock)
+	apiRoutes.GET("/themes/:id/winners", getThemeWinnersHandler, authMiddleware, readLock)
+	apiRoutes.GET("/themes/:id/near-wins", getNearWinsHandler, authMiddleware, readLock)
+	// Generates the card on first request, so it needs the write lock
+	apiRoutes.GET("/themes/:id/cards/mine", getCardByUserIdHandler, authMiddleware, writeLock)
+	apiRoutes.GET("/themes/:id/cards", getMyCardsHandler, authMiddleware, readLock)
+	apiRoutes.POST("/themes/:id/cards", addCardHandler, authMiddleware, writeLock)
+	apiRoutes.POST("/themes/:id/cards/:cardId/reroll", rerollCardHandler, authMiddleware, writeLock)
+	apiRoutes.POST("/themes/:themeId/items/:itemId/daub", daubItemHandler, authMiddleware, writeLock)
+	apiRoutes.POST("/themes/:id/claims", submitClaimHandler, authMiddleware, writeLock)
+	apiRoutes.GET("/themes/:id/teams", getTeamsHandler, authMiddleware, readLock)
+	apiRoutes.POST("/themes/:id/teams", createTeamHandler, authMiddleware, writeLock)
+	apiRoutes.POST("/themes/:id/teams/leave", leaveTeamHandler, authMiddleware, writeLock)
+	apiRoutes.POST("/themes/:id/teams/:teamId/join", joinTeamHandler, authMiddleware, writeLock)
 
 	// Admin routes
 	adminRoutes := apiRoutes.Group("/admin", authMiddleware, adminMiddleware)
 
-	adminRoutes.GET("/check", checkAdminAccessHandler)
+	adminRoutes.GET("/check", checkAdminAccessHandler, readLock)
 
 	// admin theme management
-	adminRoutes.POST("/themes/:themeId/items/:itemId/toggle", toggleItemHandler)
-	adminRoutes.POST("/themes", createThemeHandler)
-	adminRoutes.PUT("/themes/:id", updateThemeHandler)
-	adminRoutes.DELETE("/themes/:id", deleteThemeHandler)
-	adminRoutes.GET("/themes/:id/cards", getAllCardsHandler)
-	adminRoutes.POST("/themes/:id/complete", setThemeCompleteHandler)
-	adminRoutes.POST("/themes/active", setActiveThemeHandler)
+	adminRoutes.POST("/themes/:themeId/items/:itemId/toggle", toggleItemHandler, writeLock)
+	adminRoutes.POST("/themes", createThemeHandler, writeLock)
+	adminRoutes.PUT("/themes/:id", updateThemeHandler, writeLock)
+	adminRoutes.DELETE("/themes/:id", deleteThemeHandler, writeLock)
+	adminRoutes.GET("/themes/:id/cards", getAllCardsHandler, readLock)
+	adminRoutes.GET("/themes/:id/cards/:cardId/verify", verifyCardHandler, readLock)
+	// Locks only while copying the theme, not while simulating
+	adminRoutes.GET("/themes/:id/analytics", getThemeAnalyticsHandler)
+	adminRoutes.POST("/themes/:id/complete", setThemeCompleteHandler, writeLock)
+	adminRoutes.POST("/themes/:id/state", changeThemeStateHandler, writeLock)
+	adminRoutes.PUT("/themes/:id/schedule", scheduleThemeHandler, writeLock)
+	adminRoutes.POST("/themes/:id/caller/:action", callerControlHandler, writeLock)
+	adminRoutes.GET("/themes/:id/claims", getClaimsHandler, readLock)
+	adminRoutes.POST("/themes/:id/claims/:claimId/approve", approveClaimHandler, writeLock)
+	adminRoutes.POST("/themes/:id/claims/:claimId/reject", rejectClaimHandler, writeLock)
+	adminRoutes.POST("/themes/:id/clone", cloneThemeHandler, writeLock)
+	adminRoutes.POST("/themes/:id/template", saveThemeTemplateHandler, writeLock)
+	adminRoutes.POST("/themes/active", setActiveThemeHandler, writeLock)
+
+	// admin theme templates
+	adminRoutes.GET("/templates", getTemplatesHandler, readLock)
+	adminRoutes.POST("/templates", createTemplateHandler, writeLock)
+	adminRoutes.DELETE("/templates/:id", deleteTemplateHandler, writeLock)
+	adminRoutes.POST("/templates/:id/instantiate", instantiateTemplateHandler, writeLock)
+
+	// admin rooms
+	adminRoutes.POST("/rooms", createRoomHandler, writeLock)
+	adminRoutes.DELETE("/rooms/:roomId", deleteRoomHandler, writeLock)
+	adminRoutes.POST("/rooms/:roomId/active-theme", setRoomThemeHandler, writeLock)
+
+	// admin seasons
+	adminRoutes.POST("/seasons", createSeasonHandler, writeLock)
+	adminRoutes.PUT("/seasons/:id", updateSeasonHandler, writeLock)
+	adminRoutes.DELETE("/seasons/:id", deleteSeasonHandler, writeLock)
+
+	// admin backups
+	adminRoutes.GET("/backups", getBackupsHandler)
+	adminRoutes.POST("/backups", createBackupHandler, readLock)
+	adminRoutes.GET("/backups/:name", downloadBackupHandler)
+	adminRoutes.POST("/backups/restore", restoreBackupHandler, writeLock)
+
+	// admin audit trail
+	adminRoutes.GET("/events", getEventsHandler)
 
 	// WebSocket endpoint
 	e.GET("/ws", webSocketHandler)
@@ -64,8 +235,4 @@ func main() {
 
 	// Serve static files - this should be last to catch all non-API routes
 	e.GET("/*", staticHandler)
-
-	port := cmp.Or(os.Getenv("PORT"), "8080")
-	log.Printf("Server starting on port %s", port)
-	e.Logger.Fatal(e.Start(":" + port))
 }
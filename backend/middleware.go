@@ -28,6 +28,7 @@ func authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 		claims := token.Claims.(jwt.MapClaims)
 		userID := claims["user_id"].(string)
 
+		dbMutex.RLock()
 		var user *User
 		for i := range db.Users {
 			if db.Users[i].ID == userID {
@@ -35,6 +36,7 @@ func authMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 				break
 			}
 		}
+		dbMutex.RUnlock()
 
 		if user == nil {
 			return c.JSON(http.StatusUnauthorized, map[string]string{"error": "User not found"})
@@ -50,10 +52,32 @@ func adminMiddleware(next echo.HandlerFunc) echo.HandlerFunc {
 	return func(c echo.Context) error {
 		user := c.Get("user").(*User)
 
-		if slices.Contains(db.AdminDiscordIDs, user.DiscordID) {
+		dbMutex.RLock()
+		isAdmin := slices.Contains(db.AdminDiscordIDs, user.DiscordID)
+		dbMutex.RUnlock()
+
+		if isAdmin {
 			return next(c)
 		}
 
 		return c.JSON(http.StatusForbidden, map[string]string{"error": "Admin access required"})
 	}
 }
+
+// readLock holds the database read lock for the rest of the request
+func readLock(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		dbMutex.RLock()
+		defer dbMutex.RUnlock()
+		return next(c)
+	}
+}
+
+// writeLock holds the database write lock for the rest of the request
+func writeLock(next echo.HandlerFunc) echo.HandlerFunc {
+	return func(c echo.Context) error {
+		dbMutex.Lock()
+		defer dbMutex.Unlock()
+		return next(c)
+	}
+}
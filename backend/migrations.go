@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"slices"
+)
+
+// migration upgrades a Database from version-1 to version. apply mutates db
+// in place and returns a human-readable line for every change it made.
+type migration struct {
+	version     int
+	description string
+	apply       func(db *Database) []string
+}
+
+// migrations must stay ordered by version; append new ones to the end.
+var migrations = []migration{
+	{1, "move legacy bingo_cards into their theme's cards", migrateLegacyBingoCards},
+	{2, "move legacy user is_admin flags into admin_discord_ids", migrateLegacyAdminFlags},
+	{3, "set grid_size on themes created before it was configurable", migrateDefaultGridSize},
+	{4, "give every theme a card generation seed", migrateThemeSeeds},
+	{5, "key theme cards by card id instead of user id", migrateCardKeys},
+	{6, "derive a lifecycle state for every theme", migrateThemeStates},
+	{7, "record how close every card is to a win", migrateNearWins},
+	{8, "record the grid size custom win patterns were drawn for", migrateCustomPatternSizes},
+}
+
+var currentSchemaVersion = migrations[len(migrations)-1].version
+
+type migrationReport struct {
+	Version     int
+	Description string
+	Changes     []string
+}
+
+// migrateDatabase applies every migration newer than db.SchemaVersion and
+// reports what each one changed.
+func migrateDatabase(db *Database) ([]migrationReport, error) {
+	if db.SchemaVersion > currentSchemaVersion {
+		return nil, fmt.Errorf("database schema version %d is newer than this server supports (%d)", db.SchemaVersion, currentSchemaVersion)
+	}
+
+	var reports []migrationReport
+	for _, m := range migrations {
+		if m.version <= db.SchemaVersion {
+			continue
+		}
+
+		reports = append(reports, migrationReport{
+			Version:     m.version,
+			Description: m.description,
+			Changes:     m.apply(db),
+		})
+		db.SchemaVersion = m.version
+	}
+
+	return reports, nil
+}
+
+// dryRunMigrations reads the configured database and prints the migrations
+// that would run, without writing anything.
+func dryRunMigrations() error {
+	loaded, err := peekDatabase()
+	if err != nil {
+		return err
+	}
+
+	from := loaded.SchemaVersion
+	reports, err := migrateDatabase(loaded)
+	if err != nil {
+		return err
+	}
+
+	if len(reports) == 0 {
+		log.Printf("Database is at schema version %d, no migrations pending", from)
+		return nil
+	}
+
+	log.Printf("Database would be migrated from schema version %d to %d", from, loaded.SchemaVersion)
+	for _, report := range reports {
+		log.Printf("  v%d: %s", report.Version, report.Description)
+		if len(report.Changes) == 0 {
+			log.Println("    no changes")
+		}
+		for _, change := range report.Changes {
+			log.Printf("    %s", change)
+		}
+	}
+
+	return nil
+}
+
+// migrateLegacyBingoCards moves cards from the old top-level bingo_cards
+// list into their theme's Cards map. Cards whose theme is gone, or whose
+// user already has a card in that theme, are dropped.
+func migrateLegacyBingoCards(db *Database) []string {
+	var changes []string
+
+	themes := make(map[string]*Theme)
+	for _, theme := range db.Themes {
+		if theme.Cards == nil {
+			theme.Cards = make(map[string]*Card)
+		}
+		themes[theme.ID] = theme
+	}
+
+	for _, card := range db.BingoCards {
+		theme, ok := themes[card.ThemeID]
+		switch {
+		case !ok:
+			changes = append(changes, fmt.Sprintf("dropped card %s: theme %s no longer exists", card.ID, card.ThemeID))
+		case theme.Cards[card.UserID] != nil:
+			changes = append(changes, fmt.Sprintf("dropped card %s: user %s already has a card in theme %s", card.ID, card.UserID, card.ThemeID))
+		default:
+			theme.Cards[card.UserID] = card
+			changes = append(changes, fmt.Sprintf("moved card %s into theme %s", card.ID, card.ThemeID))
+		}
+	}
+	db.BingoCards = nil
+
+	return changes
+}
+
+// migrateLegacyAdminFlags keeps any admin grant stored on a user by adding
+// the user's Discord ID to AdminDiscordIDs, then clears the flag.
+func migrateLegacyAdminFlags(db *Database) []string {
+	var changes []string
+
+	for _, user := range db.Users {
+		if !user.IsAdmin {
+			continue
+		}
+
+		if !slices.Contains(db.AdminDiscordIDs, user.DiscordID) {
+			db.AdminDiscordIDs = append(db.AdminDiscordIDs, user.DiscordID)
+			changes = append(changes, fmt.Sprintf("granted admin to %s (%s)", user.Username, user.DiscordID))
+		}
+		user.IsAdmin = false
+		changes = append(changes, fmt.Sprintf("cleared is_admin on user %s", user.ID))
+	}
+
+	return changes
+}
+
+// migrateDefaultGridSize records the 5x5 grid that every theme used before
+// grid sizes were configurable.
+func migrateDefaultGridSize(db *Database) []string {
+	var changes []string
+
+	for _, theme := range db.Themes {
+		if theme.GridSize == 0 {
+			theme.GridSize = defaultGridSize
+			changes = append(changes, fmt.Sprintf("set grid_size %d on theme %s", defaultGridSize, theme.ID))
+		}
+	}
+
+	return changes
+}
+
+// migrateThemeSeeds seeds themes created before card generation was
+// reproducible. Their existing cards cannot be verified.
+func migrateThemeSeeds(db *Database) []string {
+	var changes []string
+
+	for _, theme := range db.Themes {
+		if theme.Seed == 0 {
+			theme.Seed = newThemeSeed()
+			changes = append(changes, fmt.Sprintf("seeded theme %s", theme.ID))
+		}
+	}
+
+	return changes
+}
+
+// migrateCardKeys re-keys each theme's Cards map by card ID so a player can
+// hold more than one card.
+func migrateCardKeys(db *Database) []string {
+	var changes []string
+
+	for _, theme := range db.Themes {
+		cards := make(map[string]*Card, len(theme.Cards))
+		for key, card := range theme.Cards {
+			cards[card.ID] = card
+			if key != card.ID {
+				changes = append(changes, fmt.Sprintf("re-keyed card %s in theme %s", card.ID, theme.ID))
+			}
+		}
+		theme.Cards = cards
+	}
+
+	return changes
+}
+
+// migrateThemeStates gives themes created before the lifecycle existed a
+// state: completed themes are finished, themes with marked items are live
+// and the rest stay open for cards.
+func migrateThemeStates(db *Database) []string {
+	var changes []string
+
+	for _, theme := range db.Themes {
+		if theme.State != "" {
+			continue
+		}
+
+		state := ThemeOpen
+		switch {
+		case theme.IsComplete:
+			state = ThemeFinished
+		case slices.ContainsFunc(theme.Items, func(item *Item) bool { return item.Marked }):
+			state = ThemeLive
+		}
+		theme.setState(state)
+		changes = append(changes, fmt.Sprintf("set state %s on theme %s", state, theme.ID))
+	}
+
+	return changes
+}
+
+// migrateNearWins fills in Remaining and NeededItems, which cards dealt
+// before near-win tracking lack.
+func migrateNearWins(db *Database) []string {
+	var changes []string
+
+	for _, theme := range db.Themes {
+		for _, card := range theme.Cards {
+			if !card.active() || len(card.Items) == 0 {
+				continue
+			}
+			card.checkBingo(theme)
+			changes = append(changes, fmt.Sprintf("card %s in theme %s is %d away from a win", card.ID, theme.ID, card.Remaining))
+		}
+	}
+
+	return changes
+}
+
+// migrateCustomPatternSizes records the theme's grid size on custom win
+// patterns, which were always validated against it before patterns kept
+// their own.
+func migrateCustomPatternSizes(db *Database) []string {
+	var changes []string
+
+	for _, theme := range db.Themes {
+		for i, pattern := range theme.WinPatterns {
+			if pattern.Type != PatternCustom || pattern.GridSize != 0 {
+				continue
+			}
+			theme.WinPatterns[i].GridSize = theme.gridSize()
+			changes = append(changes, fmt.Sprintf("set grid_size %d on pattern %q of theme %s", theme.gridSize(), pattern.displayName(), theme.ID))
+		}
+	}
+
+	return changes
+}
@@ -0,0 +1,177 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// dirContents maps the name of every file in dir to its contents.
+func dirContents(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	files := make(map[string]string)
+	for _, entry := range entries {
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[entry.Name()] = string(data)
+	}
+
+	return files
+}
+
+// assertDirUnchanged fails the test if dir no longer holds exactly before.
+func assertDirUnchanged(t *testing.T, dir string, before map[string]string) {
+	t.Helper()
+
+	after := dirContents(t, dir)
+	if len(after) != len(before) {
+		t.Errorf("files changed from %d to %d", len(before), len(after))
+	}
+	for name, data := range before {
+		if after[name] != data {
+			t.Errorf("%s was modified", name)
+		}
+	}
+}
+
+func TestMigrateDatabase(t *testing.T) {
+	d := &Database{
+		SchemaVersion: 4,
+		Themes: []*Theme{
+			{ID: "done", IsComplete: true},
+			{ID: "started", Items: []*Item{{ID: "i", Marked: true}}},
+			{ID: "legacy", Cards: map[string]*Card{"u1": {ID: "c1", UserID: "u1", ThemeID: "legacy"}}},
+		},
+	}
+
+	reports, err := migrateDatabase(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reports) != currentSchemaVersion-4 || d.SchemaVersion != currentSchemaVersion {
+		t.Fatalf("ran %d migrations to version %d", len(reports), d.SchemaVersion)
+	}
+
+	for id, want := range map[string]string{"done": ThemeFinished, "started": ThemeLive, "legacy": ThemeOpen} {
+		theme, _ := findTheme(d, id)
+		if theme.State != want {
+			t.Errorf("theme %s is %s, want %s", id, theme.State, want)
+		}
+	}
+	if legacy, _ := findTheme(d, "legacy"); legacy.Cards["c1"] == nil {
+		t.Errorf("cards not keyed by card ID: %v", legacy.Cards)
+	}
+
+	d.SchemaVersion = currentSchemaVersion + 1
+	if _, err := migrateDatabase(d); err == nil {
+		t.Errorf("migrated a database newer than the server")
+	}
+}
+
+func TestDryRunMigrationsJSONDoesNotWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "database.json")
+	t.Setenv("STORAGE_DRIVER", "json")
+	t.Setenv("DATABASE_PATH", path)
+
+	// A missing database is not created
+	if err := dryRunMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	assertDirUnchanged(t, dir, map[string]string{})
+
+	// A corrupt database is neither moved aside nor rewritten from its generation
+	old, err := json.Marshal(&Database{SchemaVersion: 1, ActiveThemeID: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path+".1", old, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte("{garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	before := dirContents(t, dir)
+	loaded, err := peekDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ActiveThemeID != "a" {
+		t.Errorf("peeked active theme %q, want the generation's %q", loaded.ActiveThemeID, "a")
+	}
+	if err := dryRunMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	assertDirUnchanged(t, dir, before)
+}
+
+func TestDryRunMigrationsSQLiteDoesNotWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bingo.db")
+	t.Setenv("STORAGE_DRIVER", "sqlite")
+	t.Setenv("DATABASE_PATH", path)
+
+	// A missing file is not created, nor is the JSON database next to it imported
+	legacy, err := json.Marshal(&Database{SchemaVersion: 3, ActiveThemeID: "legacy"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "database.json"), legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	before := dirContents(t, dir)
+	loaded, err := peekDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ActiveThemeID != "legacy" {
+		t.Errorf("peeked active theme %q, want the JSON database's", loaded.ActiveThemeID)
+	}
+	assertDirUnchanged(t, dir, before)
+
+	s, err := newSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.ActiveThemeID = "sqlite"
+	if err := s.SaveActiveThemeID(d.ActiveThemeID); err != nil {
+		t.Fatal(err)
+	}
+
+	// Changes still in the write-ahead log of an open database are seen
+	if loaded, err := peekDatabase(); err != nil || loaded.ActiveThemeID != "sqlite" {
+		t.Errorf("peeked %v, %v; want the uncheckpointed change", loaded, err)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	before = dirContents(t, dir)
+	if err := dryRunMigrations(); err != nil {
+		t.Fatal(err)
+	}
+	loaded, err = peekDatabase()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.ActiveThemeID != "sqlite" || loaded.SchemaVersion != 3 {
+		t.Errorf("peeked %q at version %d, want the SQLite file's contents", loaded.ActiveThemeID, loaded.SchemaVersion)
+	}
+	assertDirUnchanged(t, dir, before)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"cmp"
+	"slices"
+)
+
+// NearWins summarizes how close a theme's players are to winning, for
+// overlays that build suspense as items are called.
+type NearWins struct {
+	ThemeID string `json:"theme_id"`
+	// OneAway counts the players holding a card one square from a win
+	OneAway int `json:"one_away"`
+	// Players maps squares left to how many players are that close on
+	// their best card
+	Players map[int]int   `json:"players"`
+	Cards   []NearWinCard `json:"cards"`
+}
+
+// NearWinCard is a card one square from a win and the items that would
+// complete it.
+type NearWinCard struct {
+	CardID      string   `json:"card_id"`
+	UserID      string   `json:"user_id"`
+	TeamID      string   `json:"team_id,omitempty"`
+	NeededItems []string `json:"needed_items"`
+}
+
+// nearWins summarizes the theme's active cards. Cards are expected to have
+// been checked with checkBingo.
+func (t *Theme) nearWins() NearWins {
+	summary := NearWins{ThemeID: t.ID, Players: make(map[int]int), Cards: []NearWinCard{}}
+
+	closest := make(map[string]int)
+	for _, card := range t.Cards {
+		if !card.active() {
+			continue
+		}
+		for _, userID := range t.cardPlayers(card) {
+			if left, ok := closest[userID]; !ok || card.Remaining < left {
+				closest[userID] = card.Remaining
+			}
+		}
+		if card.Remaining == 1 {
+			summary.Cards = append(summary.Cards, NearWinCard{
+				CardID:      card.ID,
+				UserID:      card.UserID,
+				TeamID:      card.TeamID,
+				NeededItems: card.NeededItems,
+			})
+		}
+	}
+
+	for _, left := range closest {
+		summary.Players[left]++
+	}
+	summary.OneAway = summary.Players[1]
+
+	slices.SortFunc(summary.Cards, func(a, b NearWinCard) int {
+		return cmp.Compare(a.CardID, b.CardID)
+	})
+
+	return summary
+}
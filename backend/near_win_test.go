@@ -0,0 +1,100 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"slices"
+	"testing"
+)
+
+// newNearWinTheme returns a live 3x3 theme with eight items and one card
+// holding them around the free space.
+func newNearWinTheme() (*Theme, *Card) {
+	theme := newTestTheme("t1", 3, 8)
+	theme.setState(ThemeLive)
+	card := &Card{ID: "c1", UserID: "u1", ThemeID: theme.ID, Items: [][]string{{"a", "b", "c"}, {"d", freeSpaceID, "e"}, {"f", "g", "h"}}}
+	theme.Cards[card.ID] = card
+	return theme, card
+}
+
+func TestCheckBingoCountsRemainingSquares(t *testing.T) {
+	theme, card := newNearWinTheme()
+
+	// Lines through the free space need two squares
+	card.checkBingo(theme)
+	if card.Remaining != 2 || len(card.NeededItems) != 8 {
+		t.Errorf("remaining = %d needing %v, want 2 needing every item", card.Remaining, card.NeededItems)
+	}
+
+	markItems(t, theme, "a")
+	card.checkBingo(theme)
+	if card.Remaining != 1 || !slices.Equal(card.NeededItems, []string{"h"}) {
+		t.Errorf("remaining = %d needing %v, want 1 needing h", card.Remaining, card.NeededItems)
+	}
+
+	markItems(t, theme, "h")
+	card.checkBingo(theme)
+	if card.Remaining != 0 || card.NeededItems != nil || !card.IsWinner {
+		t.Errorf("remaining = %d needing %v, want a winner", card.Remaining, card.NeededItems)
+	}
+}
+
+func TestNearWins(t *testing.T) {
+	theme, card := newNearWinTheme()
+	second := &Card{ID: "c2", UserID: "u1", ThemeID: theme.ID, Index: 1, Items: [][]string{{"h", "g", "f"}, {"e", freeSpaceID, "d"}, {"c", "b", "a"}}}
+	other := &Card{ID: "c3", UserID: "u2", ThemeID: theme.ID, Items: [][]string{{"b", "c", "d"}, {"e", freeSpaceID, "f"}, {"g", "h", "a"}}}
+	theme.Cards[second.ID] = second
+	theme.Cards[other.ID] = other
+
+	markItems(t, theme, "a")
+	for _, c := range theme.Cards {
+		c.checkBingo(theme)
+	}
+
+	summary := theme.nearWins()
+	// Players count once however many of their cards are one away
+	if summary.OneAway != 2 || summary.Players[1] != 2 {
+		t.Errorf("summary = %+v, want two players one away", summary)
+	}
+	if len(summary.Cards) != 3 || summary.Cards[0].CardID != card.ID || summary.Cards[1].CardID != second.ID {
+		t.Errorf("cards = %+v, want all three cards in ID order", summary.Cards)
+	}
+}
+
+func TestMigrateNearWins(t *testing.T) {
+	theme, card := newNearWinTheme()
+	markItems(t, theme, "a", "h")
+	card.Remaining = 5
+
+	if _, err := migrateDatabase(&Database{SchemaVersion: 6, Themes: []*Theme{theme}}); err != nil {
+		t.Fatal(err)
+	}
+	if card.Remaining != 0 {
+		t.Errorf("remaining = %d after migrating a winning card, want 0", card.Remaining)
+	}
+}
+
+func TestGetNearWinsHandler(t *testing.T) {
+	useTestStore(t)
+
+	user := &User{ID: "u1"}
+	db.Users = append(db.Users, user)
+	theme, card := newNearWinTheme()
+	db.Themes = append(db.Themes, theme)
+	markItems(t, theme, "a")
+	card.checkBingo(theme)
+
+	server := newTestServer(t)
+	code, body := doRequest(t, http.MethodGet, server.URL+"/api/themes/t1/near-wins", testToken(t, user.ID), "")
+	if code != http.StatusOK {
+		t.Fatalf("near wins = %d %s", code, body)
+	}
+
+	var summary NearWins
+	if err := json.Unmarshal([]byte(body), &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.OneAway != 1 || len(summary.Cards) != 1 || !slices.Equal(summary.Cards[0].NeededItems, []string{"h"}) {
+		t.Errorf("summary = %+v, want c1 one away needing h", summary)
+	}
+}
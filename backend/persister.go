@@ -0,0 +1,412 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"sync"
+	"time"
+)
+
+// itemKey identifies an item; item IDs are only unique within a theme.
+type itemKey struct {
+	themeID string
+	itemID  string
+}
+
+// pendingSaves is the set of entities changed since the last flush. Later
+// saves of the same entity replace earlier ones.
+type pendingSaves struct {
+	users            map[string]*User
+	themes           map[string]*Theme
+	deletedThemes    map[string]bool
+	items            map[itemKey]*Item
+	cards            map[string]*Card
+	adminIDs         []string
+	adminIDsSet      bool
+	activeTheme      string
+	activeSet        bool
+	rooms            map[string]*Room
+	deletedRooms     map[string]bool
+	seasons          map[string]*Season
+	deletedSeasons   map[string]bool
+	templates        map[string]*ThemeTemplate
+	deletedTemplates map[string]bool
+}
+
+func newPendingSaves() *pendingSaves {
+	return &pendingSaves{
+		users:            make(map[string]*User),
+		themes:           make(map[string]*Theme),
+		deletedThemes:    make(map[string]bool),
+		items:            make(map[itemKey]*Item),
+		cards:            make(map[string]*Card),
+		rooms:            make(map[string]*Room),
+		deletedRooms:     make(map[string]bool),
+		seasons:          make(map[string]*Season),
+		deletedSeasons:   make(map[string]bool),
+		templates:        make(map[string]*ThemeTemplate),
+		deletedTemplates: make(map[string]bool),
+	}
+}
+
+func (p *pendingSaves) len() int {
+	n := len(p.users) + len(p.themes) + len(p.deletedThemes) + len(p.items) + len(p.cards) + len(p.rooms) + len(p.deletedRooms) +
+		len(p.seasons) + len(p.deletedSeasons) + len(p.templates) + len(p.deletedTemplates)
+	if p.adminIDsSet {
+		n++
+	}
+	if p.activeSet {
+		n++
+	}
+	return n
+}
+
+// merge adds older saves from o that p does not already supersede. It is
+// used to requeue a batch whose flush failed.
+func (p *pendingSaves) merge(o *pendingSaves) {
+	for id, user := range o.users {
+		if _, ok := p.users[id]; !ok {
+			p.users[id] = user
+		}
+	}
+	for id, theme := range o.themes {
+		if _, ok := p.themes[id]; !ok && !p.deletedThemes[id] {
+			p.themes[id] = theme
+		}
+	}
+	for id := range o.deletedThemes {
+		if _, ok := p.themes[id]; !ok {
+			p.deletedThemes[id] = true
+		}
+	}
+	for key, item := range o.items {
+		if _, ok := p.items[key]; !ok && !p.deletedThemes[key.themeID] {
+			p.items[key] = item
+		}
+	}
+	for id, card := range o.cards {
+		if _, ok := p.cards[id]; !ok && !p.deletedThemes[card.ThemeID] {
+			p.cards[id] = card
+		}
+	}
+	if o.adminIDsSet && !p.adminIDsSet {
+		p.adminIDs, p.adminIDsSet = o.adminIDs, true
+	}
+	if o.activeSet && !p.activeSet {
+		p.activeTheme, p.activeSet = o.activeTheme, true
+	}
+	for id, room := range o.rooms {
+		if _, ok := p.rooms[id]; !ok && !p.deletedRooms[id] {
+			p.rooms[id] = room
+		}
+	}
+	for id := range o.deletedRooms {
+		if _, ok := p.rooms[id]; !ok {
+			p.deletedRooms[id] = true
+		}
+	}
+	for id, season := range o.seasons {
+		if _, ok := p.seasons[id]; !ok && !p.deletedSeasons[id] {
+			p.seasons[id] = season
+		}
+	}
+	for id := range o.deletedSeasons {
+		if _, ok := p.seasons[id]; !ok {
+			p.deletedSeasons[id] = true
+		}
+	}
+	for id, template := range o.templates {
+		if _, ok := p.templates[id]; !ok && !p.deletedTemplates[id] {
+			p.templates[id] = template
+		}
+	}
+	for id := range o.deletedTemplates {
+		if _, ok := p.templates[id]; !ok {
+			p.deletedTemplates[id] = true
+		}
+	}
+}
+
+// persister is a write-behind Store. Saves are queued in memory and flushed
+// to the wrapped Store in one batch every interval, or sooner once maxPending
+// entities are waiting. Close flushes whatever is left.
+type persister struct {
+	inner      Store
+	interval   time.Duration
+	maxPending int
+
+	mu      sync.Mutex
+	pending *pendingSaves
+
+	// flushMu serializes every call into inner
+	flushMu sync.Mutex
+
+	kick chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+func newPersister(inner Store, interval time.Duration, maxPending int) *persister {
+	return &persister{
+		inner:      inner,
+		interval:   interval,
+		maxPending: maxPending,
+		pending:    newPendingSaves(),
+		kick:       make(chan struct{}, 1),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+func (p *persister) run() {
+	defer close(p.done)
+
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-p.kick:
+		case <-p.stop:
+			return
+		}
+
+		if err := p.Flush(); err != nil {
+			log.Println("Error flushing database:", err)
+		}
+	}
+}
+
+// Flush writes every pending save to the wrapped Store. It takes the
+// database read lock, so callers must not hold dbMutex.
+func (p *persister) Flush() error {
+	// Always lock dbMutex before flushMu; Replace is called with dbMutex held
+	dbMutex.RLock()
+	defer dbMutex.RUnlock()
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.mu.Lock()
+	batch := p.pending
+	p.pending = newPendingSaves()
+	p.mu.Unlock()
+
+	if batch.len() == 0 {
+		return nil
+	}
+
+	var dropped error
+	err := p.inner.Batch(func() error {
+		dropped = p.apply(batch)
+		return nil
+	})
+
+	if err != nil {
+		// The write itself failed; try the whole batch again next time
+		p.mu.Lock()
+		p.pending.merge(batch)
+		p.mu.Unlock()
+		return err
+	}
+
+	if dropped != nil {
+		return fmt.Errorf("dropped saves that failed: %w", dropped)
+	}
+	return nil
+}
+
+// apply passes every save in batch to the wrapped Store. A save that fails
+// is skipped rather than retried, so one bad entity cannot hold back every
+// other change; their errors are returned together.
+func (p *persister) apply(batch *pendingSaves) error {
+	var errs []error
+	check := func(kind, id string, err error) {
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", kind, id, err))
+		}
+	}
+
+	for id := range batch.deletedThemes {
+		check("deleting theme", id, p.inner.DeleteTheme(id))
+	}
+	for id, user := range batch.users {
+		check("user", id, p.inner.SaveUser(user))
+	}
+	for id, theme := range batch.themes {
+		check("theme", id, p.inner.SaveTheme(theme))
+	}
+	for key, item := range batch.items {
+		check("item", key.themeID+"/"+key.itemID, p.inner.SaveItem(key.themeID, item))
+	}
+	for id, card := range batch.cards {
+		check("card", id, p.inner.SaveCard(card))
+	}
+	if batch.adminIDsSet {
+		check("admin IDs", "", p.inner.SaveAdminDiscordIDs(batch.adminIDs))
+	}
+	if batch.activeSet {
+		check("active theme", batch.activeTheme, p.inner.SaveActiveThemeID(batch.activeTheme))
+	}
+	for id := range batch.deletedRooms {
+		check("deleting room", id, p.inner.DeleteRoom(id))
+	}
+	for id, room := range batch.rooms {
+		check("room", id, p.inner.SaveRoom(room))
+	}
+	for id := range batch.deletedSeasons {
+		check("deleting season", id, p.inner.DeleteSeason(id))
+	}
+	for id, season := range batch.seasons {
+		check("season", id, p.inner.SaveSeason(season))
+	}
+	for id := range batch.deletedTemplates {
+		check("deleting template", id, p.inner.DeleteTemplate(id))
+	}
+	for id, template := range batch.templates {
+		check("template", id, p.inner.SaveTemplate(template))
+	}
+
+	return errors.Join(errs...)
+}
+
+// queue records a save and wakes the flusher once enough are waiting.
+func (p *persister) queue(fn func(pending *pendingSaves)) error {
+	p.mu.Lock()
+	fn(p.pending)
+	full := p.pending.len() >= p.maxPending
+	p.mu.Unlock()
+
+	if full {
+		select {
+		case p.kick <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (p *persister) Load() (*Database, error) {
+	return p.inner.Load()
+}
+
+// Replace drops every pending save, since db supersedes them, and writes
+// db straight through. Like the other saves, the caller holds dbMutex.
+func (p *persister) Replace(db *Database) error {
+	p.flushMu.Lock()
+	defer p.flushMu.Unlock()
+
+	p.mu.Lock()
+	p.pending = newPendingSaves()
+	p.mu.Unlock()
+
+	return p.inner.Replace(db)
+}
+
+// Batch runs fn directly; its saves are queued like any other.
+func (p *persister) Batch(fn func() error) error {
+	return fn()
+}
+
+func (p *persister) SaveUser(user *User) error {
+	return p.queue(func(pending *pendingSaves) {
+		pending.users[user.ID] = user
+	})
+}
+
+func (p *persister) SaveTheme(theme *Theme) error {
+	return p.queue(func(pending *pendingSaves) {
+		delete(pending.deletedThemes, theme.ID)
+		pending.themes[theme.ID] = theme
+	})
+}
+
+func (p *persister) DeleteTheme(themeID string) error {
+	return p.queue(func(pending *pendingSaves) {
+		delete(pending.themes, themeID)
+		maps.DeleteFunc(pending.items, func(key itemKey, _ *Item) bool {
+			return key.themeID == themeID
+		})
+		maps.DeleteFunc(pending.cards, func(_ string, card *Card) bool {
+			return card.ThemeID == themeID
+		})
+		pending.deletedThemes[themeID] = true
+	})
+}
+
+func (p *persister) SaveItem(themeID string, item *Item) error {
+	return p.queue(func(pending *pendingSaves) {
+		pending.items[itemKey{themeID, item.ID}] = item
+	})
+}
+
+func (p *persister) SaveCard(card *Card) error {
+	return p.queue(func(pending *pendingSaves) {
+		pending.cards[card.ID] = card
+	})
+}
+
+func (p *persister) SaveAdminDiscordIDs(ids []string) error {
+	return p.queue(func(pending *pendingSaves) {
+		pending.adminIDs, pending.adminIDsSet = ids, true
+	})
+}
+
+func (p *persister) SaveActiveThemeID(themeID string) error {
+	return p.queue(func(pending *pendingSaves) {
+		pending.activeTheme, pending.activeSet = themeID, true
+	})
+}
+
+func (p *persister) SaveRoom(room *Room) error {
+	return p.queue(func(pending *pendingSaves) {
+		delete(pending.deletedRooms, room.ID)
+		pending.rooms[room.ID] = room
+	})
+}
+
+func (p *persister) DeleteRoom(roomID string) error {
+	return p.queue(func(pending *pendingSaves) {
+		delete(pending.rooms, roomID)
+		pending.deletedRooms[roomID] = true
+	})
+}
+
+func (p *persister) SaveSeason(season *Season) error {
+	return p.queue(func(pending *pendingSaves) {
+		delete(pending.deletedSeasons, season.ID)
+		pending.seasons[season.ID] = season
+	})
+}
+
+func (p *persister) DeleteSeason(seasonID string) error {
+	return p.queue(func(pending *pendingSaves) {
+		delete(pending.seasons, seasonID)
+		pending.deletedSeasons[seasonID] = true
+	})
+}
+
+func (p *persister) SaveTemplate(template *ThemeTemplate) error {
+	return p.queue(func(pending *pendingSaves) {
+		delete(pending.deletedTemplates, template.ID)
+		pending.templates[template.ID] = template
+	})
+}
+
+func (p *persister) DeleteTemplate(templateID string) error {
+	return p.queue(func(pending *pendingSaves) {
+		delete(pending.templates, templateID)
+		pending.deletedTemplates[templateID] = true
+	})
+}
+
+// Close stops the background flusher, writes any pending saves and closes
+// the wrapped Store.
+func (p *persister) Close() error {
+	close(p.stop)
+	<-p.done
+
+	return errors.Join(p.Flush(), p.inner.Close())
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// faultyStore wraps a Store, failing saves of the theme with ID badTheme and
+// the next failBatches batches as a whole.
+type faultyStore struct {
+	Store
+	badTheme    string
+	failBatches int
+}
+
+func (s *faultyStore) SaveTheme(theme *Theme) error {
+	if theme.ID == s.badTheme {
+		return errors.New("bad theme")
+	}
+	return s.Store.SaveTheme(theme)
+}
+
+func (s *faultyStore) Batch(fn func() error) error {
+	if s.failBatches > 0 {
+		s.failBatches--
+		return errors.New("disk unavailable")
+	}
+	return s.Store.Batch(fn)
+}
+
+// newTestPersister returns a persister over a SQLite store that only
+// flushes when asked, and the SQLite store's path.
+func newTestPersister(t *testing.T, wrap func(Store) Store) (*persister, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "bingo.db")
+	inner, err := newSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := newPersister(wrap(inner), time.Hour, 1000)
+	go p.run()
+
+	d, err := p.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	useDatabase(t, d, p)
+
+	return p, path
+}
+
+// loadSQLite reads what has been written to the SQLite file at path.
+func loadSQLite(t *testing.T, path string) *Database {
+	t.Helper()
+
+	s, err := newSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	d, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestPersisterBatchesUntilFlush(t *testing.T) {
+	p, path := newTestPersister(t, func(s Store) Store { return s })
+	defer p.Close()
+
+	user := &User{ID: "u1"}
+	if err := p.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if d := loadSQLite(t, path); len(d.Users) != 0 {
+		t.Fatalf("user written before the flush")
+	}
+
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if d := loadSQLite(t, path); len(d.Users) != 1 {
+		t.Fatalf("user not written by the flush")
+	}
+}
+
+func TestPersisterDropsFailedSaves(t *testing.T) {
+	p, path := newTestPersister(t, func(s Store) Store {
+		return &faultyStore{Store: s, badTheme: "bad"}
+	})
+
+	if err := p.SaveTheme(newTestTheme("bad", 3, 9)); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.SaveUser(&User{ID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := p.Flush(); err == nil {
+		t.Errorf("failed save not reported")
+	}
+	if d := loadSQLite(t, path); len(d.Users) != 1 {
+		t.Fatalf("user held back by a failing theme")
+	}
+
+	// The failed save is not retried and does not block later ones
+	if err := p.SaveUser(&User{ID: "u2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	if d := loadSQLite(t, path); len(d.Users) != 2 {
+		t.Fatalf("got %d users, want 2", len(d.Users))
+	}
+}
+
+func TestPersisterRetriesFailedBatch(t *testing.T) {
+	p, path := newTestPersister(t, func(s Store) Store {
+		return &faultyStore{Store: s, failBatches: 1}
+	})
+
+	if err := p.SaveUser(&User{ID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Flush(); err == nil {
+		t.Fatal("failed batch not reported")
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if d := loadSQLite(t, path); len(d.Users) != 1 {
+		t.Fatalf("user from the failed batch was lost")
+	}
+}
+
+func TestPersisterKeysItemsByTheme(t *testing.T) {
+	p, path := newTestPersister(t, func(s Store) Store { return s })
+
+	// Both themes have an item "a"
+	first, second := newTestTheme("t1", 3, 9), newTestTheme("t2", 3, 9)
+	for _, theme := range []*Theme{first, second} {
+		if err := p.SaveTheme(theme); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, theme := range []*Theme{first, second} {
+		theme.Items[0].Marked = true
+		if err := p.SaveItem(theme.ID, theme.Items[0]); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, theme := range loadSQLite(t, path).Themes {
+		if !theme.Items[0].Marked {
+			t.Errorf("mark on item a of theme %s was lost", theme.ID)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// rejectClaimHandler turns down a pending claim. With penalize set, the
+// player cannot claim again for the theme's penalty period.
+func rejectClaimHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID  string `param:"id"`
+		ClaimID  string `param:"claimId"`
+		Reason   string `json:"reason"`
+		Penalize bool   `json:"penalize"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	if req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "A reason is required"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	claim, found := theme.getClaim(req.ClaimID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Claim not found"})
+	}
+
+	if claim.Status != ClaimPending {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Claim has already been resolved"})
+	}
+
+	before := eventState(claim)
+	theme.resolve(claim, user, ClaimRejected, req.Reason, req.Penalize)
+
+	if err := store.SaveTheme(theme); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventClaimResolved, user, theme.ID, claim.ID, before, claim)
+
+	broadcastThemeUpdate(theme.ID, "claim_rejected", claim)
+
+	return c.JSON(http.StatusOK, claim)
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// rerollCardHandler replaces one of the current user's cards with a newly
+// dealt one while they have rerolls left and the game has not started.
+func rerollCardHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID string `param:"id"`
+		CardID  string `param:"cardId"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	holderID, err := theme.holderID(user)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	card, found := theme.Cards[req.CardID]
+	if !found || card.holderID() != holderID || !card.active() {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Card not found"})
+	}
+
+	if theme.cardsLocked() {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "The game has started, cards are locked"})
+	}
+
+	if theme.rerolls(holderID) >= theme.MaxRerolls {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "You have no rerolls left"})
+	}
+
+	before := eventState(card)
+	replacement, err := theme.rerollCard(card, user)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	if err := store.SaveCard(card); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventCardRerolled, user, theme.ID, card.ID, before, card)
+
+	if err := store.SaveCard(replacement); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventCardCreated, user, theme.ID, replacement.ID, nil, replacement)
+
+	broadcastThemeUpdate(theme.ID, "card_rerolled", map[string]any{
+		"retired": card,
+		"card":    replacement,
+	})
+
+	return c.JSON(http.StatusOK, replacement)
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// restoreBackupHandler replaces the whole database with an uploaded
+// snapshot, sent either as the "file" field of a multipart form or as the
+// raw JSON request body. The current state is saved as a pre-restore
+// backup first.
+func restoreBackupHandler(c echo.Context) error {
+	var body io.Reader = c.Request().Body
+	if fileHeader, err := c.FormFile("file"); err == nil {
+		file, err := fileHeader.Open()
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read uploaded file"})
+		}
+		defer file.Close()
+		body = file
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read backup"})
+	}
+
+	restored, err := parseBackup(data)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	// Keep configured admins so a restore cannot lock them out
+	addEnvAdminIDs(restored)
+
+	safety, err := createBackup(backupPreRestore)
+	if err != nil {
+		c.Logger().Error("Error creating pre-restore backup:", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to back up current database"})
+	}
+
+	if err := store.Replace(restored); err != nil {
+		c.Logger().Error("Error restoring database:", err)
+		// Put the store back in step with the data still being served
+		if err := store.Replace(db); err != nil {
+			c.Logger().Error("Error reverting database:", err)
+		}
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to restore database"})
+	}
+	db = restored
+	recordEvent(EventDatabaseRestored, c.Get("user").(*User), "", "", nil, db)
+
+	broadcastUpdate("database_restored", map[string]any{
+		"active_theme_id": db.ActiveThemeID,
+	})
+
+	return c.JSON(http.StatusOK, map[string]any{
+		"message":            "Database restored",
+		"pre_restore_backup": safety,
+	})
+}
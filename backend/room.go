@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"time"
+)
+
+// defaultRoomID names the room backed by Database.ActiveThemeID. Clients
+// that do not pick a room play in it.
+const defaultRoomID = "default"
+
+// Room is a channel with its own active theme, so several games can run at
+// once. A theme is active in at most one room.
+type Room struct {
+	ID            string    `json:"id"`
+	Name          string    `json:"name"`
+	ActiveThemeID string    `json:"active_theme_id"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// allRooms returns the default room followed by the rooms created by
+// admins. The default room is built from db.ActiveThemeID on every call.
+func allRooms() []*Room {
+	rooms := []*Room{{ID: defaultRoomID, Name: "Main", ActiveThemeID: db.ActiveThemeID}}
+	return append(rooms, db.Rooms...)
+}
+
+func findRoom(roomID string) (*Room, bool) {
+	for _, room := range allRooms() {
+		if room.ID == roomID {
+			return room, true
+		}
+	}
+	return nil, false
+}
+
+// themeRoom returns the room the theme is active in.
+func themeRoom(themeID string) (*Room, bool) {
+	if themeID == "" {
+		return nil, false
+	}
+	for _, room := range allRooms() {
+		if room.ActiveThemeID == themeID {
+			return room, true
+		}
+	}
+	return nil, false
+}
+
+// checkRoomTheme reports why themeID cannot become the room's active theme.
+// An empty themeID clears the room and is always allowed.
+func checkRoomTheme(room *Room, themeID string) error {
+	if themeID == "" {
+		return nil
+	}
+
+	theme, ok := findTheme(db, themeID)
+	if !ok {
+		return errors.New("Theme not found")
+	}
+	if theme.IsComplete {
+		return errors.New("Cannot set a completed theme as active")
+	}
+	if theme.State == ThemeDraft {
+		return errors.New("Cannot set a draft theme as active")
+	}
+	if other, ok := themeRoom(themeID); ok && other.ID != room.ID {
+		return errors.New("Theme is already active in room " + other.Name)
+	}
+	return nil
+}
+
+// setRoomTheme makes themeID the room's active theme, or clears it when
+// themeID is empty, then saves, records and broadcasts the change. Callers
+// hold dbMutex and have checked the theme with checkRoomTheme.
+func setRoomTheme(room *Room, actor *User, themeID string) {
+	previous := room.ActiveThemeID
+	before := *room
+	room.ActiveThemeID = themeID
+
+	if room.ID == defaultRoomID {
+		db.ActiveThemeID = themeID
+		if err := store.SaveActiveThemeID(themeID); err != nil {
+			log.Println("Error saving database:", err)
+		}
+		recordEvent(EventActiveThemeChanged, actor, themeID, "", previous, themeID)
+		// Clients in other rooms keep their own active theme
+		broadcastTo("theme_changed", themeID, func(roomID string) bool {
+			return roomID == "" || roomID == defaultRoomID
+		})
+	} else {
+		if err := store.SaveRoom(room); err != nil {
+			log.Println("Error saving database:", err)
+		}
+		recordEvent(EventRoomUpdated, actor, themeID, room.ID, before, room)
+	}
+
+	broadcastUpdate("room_theme_changed", map[string]any{
+		"room_id":           room.ID,
+		"active_theme_id":   themeID,
+		"previous_theme_id": previous,
+	})
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRoomsKeepTheirOwnThemes(t *testing.T) {
+	useTestStore(t)
+
+	a := newTestTheme("a", 3, 9)
+	b := newTestTheme("b", 3, 9)
+	b.setState(ThemeLive)
+	room := &Room{ID: "r1", Name: "Side room", CreatedAt: time.Now()}
+	db.Themes = append(db.Themes, a, b)
+	db.Rooms = append(db.Rooms, room)
+
+	defaultRoom, _ := findRoom(defaultRoomID)
+	setRoomTheme(defaultRoom, nil, "a")
+	if db.ActiveThemeID != "a" {
+		t.Errorf("default room theme = %q, want a", db.ActiveThemeID)
+	}
+
+	if err := checkRoomTheme(room, "a"); err == nil {
+		t.Error("theme active in the default room can be made active in another")
+	}
+	if err := checkRoomTheme(room, "b"); err != nil {
+		t.Fatal(err)
+	}
+	setRoomTheme(room, nil, "b")
+	if got, ok := themeRoom("b"); !ok || got.ID != room.ID {
+		t.Errorf("theme b is active in %v, want %s", got, room.ID)
+	}
+	if err := validateDatabase(db); err != nil {
+		t.Errorf("database with one theme per room is invalid: %v", err)
+	}
+
+	if err := changeThemeState(b, nil, ThemeFinished); err != nil {
+		t.Fatal(err)
+	}
+	if room.ActiveThemeID != "" {
+		t.Errorf("room still plays finished theme %s", room.ActiveThemeID)
+	}
+
+	room.ActiveThemeID = "a"
+	if err := validateDatabase(db); err == nil {
+		t.Error("database with a theme active in two rooms is valid")
+	}
+}
+
+func TestSQLiteStoreRooms(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bingo.db")
+	s := openTestSQLiteStore(t, path)
+	if _, err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	kept := &Room{ID: "r1", Name: "Kept", ActiveThemeID: "t1", CreatedAt: time.Now()}
+	deleted := &Room{ID: "r2", Name: "Deleted", CreatedAt: time.Now()}
+	for _, room := range []*Room{kept, deleted} {
+		if err := s.SaveRoom(room); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.DeleteRoom(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := openTestSQLiteStore(t, path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Rooms) != 1 || loaded.Rooms[0].ID != kept.ID || loaded.Rooms[0].ActiveThemeID != "t1" {
+		t.Errorf("rooms = %+v, want only %s playing t1", loaded.Rooms, kept.ID)
+	}
+}
+
+func TestRoomHandlers(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "admin-discord"}
+	player := &User{ID: "u1"}
+	db.Users = append(db.Users, admin, player)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	theme := newTestTheme("t1", 3, 9)
+	theme.Seed = 42
+	db.Themes = append(db.Themes, theme)
+
+	server := newTestServer(t)
+	adminToken := testToken(t, admin.ID)
+
+	if code, _ := doRequest(t, http.MethodPost, server.URL+"/api/admin/rooms", adminToken, `{"name": "  "}`); code != http.StatusBadRequest {
+		t.Errorf("room without a name = %d, want %d", code, http.StatusBadRequest)
+	}
+	code, body := doRequest(t, http.MethodPost, server.URL+"/api/admin/rooms", adminToken, `{"name": "Side room"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("create room = %d %s", code, body)
+	}
+	var room Room
+	if err := json.Unmarshal([]byte(body), &room); err != nil {
+		t.Fatal(err)
+	}
+
+	roomURL := server.URL + "/api/admin/rooms/" + room.ID + "/active-theme"
+	if code, body := doRequest(t, http.MethodPost, roomURL, adminToken, `{"theme_id": "t1"}`); code != http.StatusOK {
+		t.Fatalf("set room theme = %d %s", code, body)
+	}
+
+	code, body = doRequest(t, http.MethodGet, server.URL+"/api/rooms/"+room.ID, testToken(t, player.ID), "")
+	if code != http.StatusOK {
+		t.Fatalf("get room = %d %s", code, body)
+	}
+	if !strings.Contains(body, `"active_theme_id":"t1"`) {
+		t.Errorf("room does not play t1: %s", body)
+	}
+	if strings.Contains(body, `"seed"`) {
+		t.Errorf("players were sent the seed: %s", body)
+	}
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// saveThemeTemplateHandler saves a theme's items and settings as a
+// template. Fields in the body override the theme's values.
+func saveThemeTemplateHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	theme, found := getThemeByID(c.Param("id"))
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	fields := themeFields{
+		Name:          theme.Name,
+		Description:   theme.Description,
+		Items:         theme.itemSpecs(),
+		ThemeSettings: theme.settings(),
+	}
+
+	var req themeOverrides
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	req.apply(&fields)
+
+	template, err := newTemplate(fields)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	addTemplate(template, user)
+
+	return c.JSON(http.StatusCreated, template)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// scheduleThemeHandler sets when a theme goes live and when it finishes.
+// Either time may be null to clear it.
+func scheduleThemeHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID  string     `param:"id"`
+		StartsAt *time.Time `json:"starts_at"`
+		EndsAt   *time.Time `json:"ends_at"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "End time must be after the start time"})
+	}
+
+	if theme.State == ThemeFinished || theme.State == ThemeArchived {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Theme has already finished"})
+	}
+
+	before := eventState(theme)
+	theme.StartsAt = req.StartsAt
+	theme.EndsAt = req.EndsAt
+
+	if err := store.SaveTheme(theme); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventThemeUpdated, user, theme.ID, theme.ID, before, theme)
+
+	broadcastUpdate("theme_updated", forPlayers(theme))
+
+	return c.JSON(http.StatusOK, theme)
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+	"time"
+)
+
+// Season groups themes whose results add up to one leaderboard.
+type Season struct {
+	ID        string        `json:"id"`
+	Name      string        `json:"name"`
+	ThemeIDs  []string      `json:"theme_ids"`
+	Scoring   *ScoringRules `json:"scoring,omitempty"`
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// ScoringRules turn a theme's results into points. PlacementPoints[i] is
+// awarded to the player or team whose best card won (i+1)th, PatternBonus
+// adds points for every standing win of the named pattern and every player
+// dealt a card gets ParticipationPoints.
+type ScoringRules struct {
+	PlacementPoints     []int          `json:"placement_points"`
+	PatternBonus        map[string]int `json:"pattern_bonus,omitempty"`
+	ParticipationPoints int            `json:"participation_points"`
+}
+
+// defaultScoring is used by the all-time leaderboard and by seasons that do
+// not set their own rules.
+var defaultScoring = ScoringRules{
+	PlacementPoints:     []int{10, 6, 3},
+	ParticipationPoints: 1,
+}
+
+// LeaderboardEntry is one player's standing across a set of themes.
+type LeaderboardEntry struct {
+	Rank     int    `json:"rank"`
+	UserID   string `json:"user_id"`
+	Username string `json:"username"`
+	Points   int    `json:"points"`
+	// Themes counts the themes the player was dealt a card in
+	Themes      int `json:"themes"`
+	Bingos      int `json:"bingos"`
+	FirstPlaces int `json:"first_places"`
+}
+
+func (s *Season) scoring() ScoringRules {
+	if s.Scoring == nil {
+		return defaultScoring
+	}
+	return *s.Scoring
+}
+
+// validate checks that points are not negative.
+func (r ScoringRules) validate() error {
+	if r.ParticipationPoints < 0 || slices.ContainsFunc(r.PlacementPoints, func(p int) bool { return p < 0 }) {
+		return errors.New("points cannot be negative")
+	}
+	for _, bonus := range r.PatternBonus {
+		if bonus < 0 {
+			return errors.New("points cannot be negative")
+		}
+	}
+	return nil
+}
+
+// validateSeasonThemes checks that every theme ID names an existing theme
+// and appears once.
+func validateSeasonThemes(themeIDs []string) error {
+	for i, id := range themeIDs {
+		if _, ok := getThemeByID(id); !ok {
+			return errors.New("Theme not found: " + id)
+		}
+		if slices.Contains(themeIDs[:i], id) {
+			return errors.New("Theme listed twice: " + id)
+		}
+	}
+	return nil
+}
+
+func getSeasonByID(seasonID string) (*Season, bool) {
+	for _, season := range db.Seasons {
+		if season.ID == seasonID {
+			return season, true
+		}
+	}
+	return nil, false
+}
+
+// cardPlayers returns the players credited with a card's results: every
+// member of the team sharing it, or the player holding it.
+func (t *Theme) cardPlayers(card *Card) []string {
+	if team, ok := t.getTeam(card.TeamID); ok {
+		return team.Members
+	}
+	return []string{card.UserID}
+}
+
+// leaderboard scores themes with rules and ranks the players by points.
+// Ties are broken by first places, then bingos.
+func leaderboard(themes []*Theme, rules ScoringRules) []LeaderboardEntry {
+	entries := make(map[string]*LeaderboardEntry)
+	entry := func(userID string) *LeaderboardEntry {
+		e, ok := entries[userID]
+		if !ok {
+			e = &LeaderboardEntry{UserID: userID}
+			entries[userID] = e
+		}
+		return e
+	}
+
+	for _, theme := range themes {
+		played := make(map[string]bool)
+		for _, card := range theme.Cards {
+			for _, userID := range theme.cardPlayers(card) {
+				played[userID] = true
+			}
+		}
+		for userID := range played {
+			e := entry(userID)
+			e.Themes++
+			e.Points += rules.ParticipationPoints
+		}
+
+		// Players and teams are placed by their best card, so holding
+		// several cards cannot take more than one place. Places go to the
+		// players who held the card when it won, not whoever holds it now.
+		placed := make(map[string]bool)
+		for _, place := range theme.ranking() {
+			holderID := cmp.Or(place.TeamID, place.UserID)
+			if _, ok := theme.Cards[place.CardID]; !ok || placed[holderID] {
+				continue
+			}
+			i := len(placed)
+			placed[holderID] = true
+			for _, userID := range place.Players {
+				e := entry(userID)
+				if i < len(rules.PlacementPoints) {
+					e.Points += rules.PlacementPoints[i]
+				}
+				if i == 0 {
+					e.FirstPlaces++
+				}
+			}
+		}
+
+		for _, win := range theme.Wins {
+			if _, ok := theme.Cards[win.CardID]; !ok || win.RevokedAt != nil {
+				continue
+			}
+			for _, userID := range theme.winPlayers(win) {
+				e := entry(userID)
+				e.Bingos++
+				e.Points += rules.PatternBonus[win.Pattern]
+			}
+		}
+	}
+
+	usernames := make(map[string]string, len(db.Users))
+	for _, user := range db.Users {
+		usernames[user.ID] = user.Username
+	}
+
+	board := make([]LeaderboardEntry, 0, len(entries))
+	for _, e := range entries {
+		e.Username = usernames[e.UserID]
+		board = append(board, *e)
+	}
+	slices.SortFunc(board, func(a, b LeaderboardEntry) int {
+		return cmp.Or(
+			cmp.Compare(b.Points, a.Points),
+			cmp.Compare(b.FirstPlaces, a.FirstPlaces),
+			cmp.Compare(b.Bingos, a.Bingos),
+			cmp.Compare(a.Username, b.Username),
+			cmp.Compare(a.UserID, b.UserID),
+		)
+	})
+
+	// Players level on points, first places and bingos share a rank
+	for i := range board {
+		board[i].Rank = i + 1
+		if i > 0 {
+			prev, cur := board[i-1], board[i]
+			if prev.Points == cur.Points && prev.FirstPlaces == cur.FirstPlaces && prev.Bingos == cur.Bingos {
+				board[i].Rank = prev.Rank
+			}
+		}
+	}
+
+	return board
+}
+
+// themes returns the season's themes that still exist.
+func (s *Season) themes() []*Theme {
+	var themes []*Theme
+	for _, id := range s.ThemeIDs {
+		if theme, ok := getThemeByID(id); ok {
+			themes = append(themes, theme)
+		}
+	}
+	return themes
+}
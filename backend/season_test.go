@@ -0,0 +1,210 @@
+package main
+
+import (
+	"net/http"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newSeasonTheme returns a live theme with one card per player, keyed by
+// theme ID followed by user ID, and the given wins.
+func newSeasonTheme(id string, userIDs []string, wins ...*Win) *Theme {
+	theme := newTestTheme(id, 3, 9)
+	theme.setState(ThemeLive)
+	for _, userID := range userIDs {
+		cardID := id + userID
+		theme.Cards[cardID] = &Card{ID: cardID, UserID: userID, ThemeID: id}
+	}
+	theme.Wins = wins
+	return theme
+}
+
+// pointsByUser returns each player's points on board.
+func pointsByUser(board []LeaderboardEntry) map[string]int {
+	points := make(map[string]int, len(board))
+	for _, e := range board {
+		points[e.UserID] = e.Points
+	}
+	return points
+}
+
+func TestLeaderboard(t *testing.T) {
+	useTestStore(t)
+
+	db.Users = append(db.Users, &User{ID: "a", Username: "A"}, &User{ID: "b", Username: "B"}, &User{ID: "c", Username: "C"})
+	now := time.Now()
+	players := []string{"a", "b", "c"}
+	db.Themes = append(db.Themes,
+		newSeasonTheme("t1", players,
+			&Win{CardID: "t1a", UserID: "a", Pattern: "Line", WonAt: now},
+			&Win{CardID: "t1b", UserID: "b", Pattern: "Blackout", WonAt: now.Add(time.Second)},
+		),
+		newSeasonTheme("t2", players,
+			&Win{CardID: "t2b", UserID: "b", Pattern: "Line", WonAt: now},
+		),
+	)
+
+	// b: 2 participation + 6 + 10, a: 2 participation + 10, c: 2 participation
+	board := leaderboard(db.Themes, defaultScoring)
+	want := []struct {
+		userID string
+		points int
+		rank   int
+	}{{"b", 18, 1}, {"a", 12, 2}, {"c", 2, 3}}
+	if len(board) != len(want) {
+		t.Fatalf("board = %+v, want %d players", board, len(want))
+	}
+	for i, w := range want {
+		if board[i].UserID != w.userID || board[i].Points != w.points || board[i].Rank != w.rank {
+			t.Errorf("place %d = %+v, want %s with %d points ranked %d", i+1, board[i], w.userID, w.points, w.rank)
+		}
+	}
+
+	rules := ScoringRules{PlacementPoints: []int{1}, PatternBonus: map[string]int{"Blackout": 20}}
+	season := &Season{ID: "s1", Name: "Season", ThemeIDs: []string{"t1", "gone"}, Scoring: &rules}
+	board = leaderboard(season.themes(), season.scoring())
+	if points := pointsByUser(board); points["b"] != 20 || points["a"] != 1 {
+		t.Errorf("points with season rules = %v, want b 20 and a 1", points)
+	}
+}
+
+func TestLeaderboardPlacesPlayersByBestCard(t *testing.T) {
+	useTestStore(t)
+
+	now := time.Now()
+	theme := newSeasonTheme("t1", []string{"a", "b"},
+		&Win{CardID: "t1a", UserID: "a", Pattern: "Line", WonAt: now},
+		&Win{CardID: "second", UserID: "a", Pattern: "Line", WonAt: now.Add(time.Second)},
+		&Win{CardID: "t1b", UserID: "b", Pattern: "Line", WonAt: now.Add(2 * time.Second)},
+	)
+	theme.Cards["second"] = &Card{ID: "second", UserID: "a", ThemeID: "t1", Index: 1}
+
+	board := leaderboard([]*Theme{theme}, defaultScoring)
+	points := pointsByUser(board)
+	// a takes first place once; b's card came third but b placed second
+	if points["a"] != 11 || points["b"] != 7 {
+		t.Errorf("points = %v, want a 11 and b 7", points)
+	}
+	if board[0].UserID != "a" || board[0].FirstPlaces != 1 || board[0].Bingos != 2 {
+		t.Errorf("leader = %+v, want a with one first place and two bingos", board[0])
+	}
+}
+
+func TestLeaderboardPlacesTeamsByBestCard(t *testing.T) {
+	useTestStore(t)
+
+	now := time.Now()
+	theme := newSeasonTheme("t1", nil,
+		&Win{CardID: "red1", TeamID: "red", Pattern: "Line", WonAt: now},
+		&Win{CardID: "red2", TeamID: "red", Pattern: "Line", WonAt: now.Add(time.Second)},
+		&Win{CardID: "solo", UserID: "c", Pattern: "Line", WonAt: now.Add(2 * time.Second)},
+	)
+	theme.TeamMode = true
+	theme.Teams = []*Team{{ID: "red", Name: "Red", Members: []string{"a", "b"}}}
+	theme.Cards["red1"] = &Card{ID: "red1", TeamID: "red", ThemeID: "t1"}
+	theme.Cards["red2"] = &Card{ID: "red2", TeamID: "red", ThemeID: "t1", Index: 1}
+	theme.Cards["solo"] = &Card{ID: "solo", UserID: "c", ThemeID: "t1"}
+
+	points := pointsByUser(leaderboard([]*Theme{theme}, defaultScoring))
+	if points["a"] != 11 || points["b"] != 11 || points["c"] != 7 {
+		t.Errorf("points = %v, want a and b 11, c 7", points)
+	}
+}
+
+func TestSQLiteStoreSeasons(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bingo.db")
+	s := openTestSQLiteStore(t, path)
+	if _, err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	rules := ScoringRules{PlacementPoints: []int{1}, PatternBonus: map[string]int{"Blackout": 20}}
+	if err := s.SaveSeason(&Season{ID: "s1", Name: "Season", ThemeIDs: []string{"t1"}, Scoring: &rules}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := openTestSQLiteStore(t, path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Seasons) != 1 || loaded.Seasons[0].Scoring.PatternBonus["Blackout"] != 20 {
+		t.Errorf("seasons = %+v, want s1 with its scoring rules", loaded.Seasons)
+	}
+}
+
+func TestSeasonHandlers(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", Username: "Admin", DiscordID: "admin-discord"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	db.Themes = append(db.Themes, newSeasonTheme("t1", []string{admin.ID}))
+
+	server := newTestServer(t)
+	token := testToken(t, admin.ID)
+	seasonsURL := server.URL + "/api/admin/seasons"
+
+	if code, _ := doRequest(t, http.MethodPost, seasonsURL, token, `{"name": "Season", "theme_ids": ["missing"]}`); code != http.StatusBadRequest {
+		t.Errorf("season with a missing theme = %d, want %d", code, http.StatusBadRequest)
+	}
+	body := `{"name": "Season", "theme_ids": ["t1"], "scoring": {"placement_points": [-1]}}`
+	if code, _ := doRequest(t, http.MethodPost, seasonsURL, token, body); code != http.StatusBadRequest {
+		t.Errorf("season with negative points = %d, want %d", code, http.StatusBadRequest)
+	}
+	if code, body := doRequest(t, http.MethodPost, seasonsURL, token, `{"name": "Season", "theme_ids": ["t1"]}`); code != http.StatusCreated {
+		t.Fatalf("create season = %d %s", code, body)
+	}
+
+	url := server.URL + "/api/seasons/" + db.Seasons[0].ID + "/leaderboard"
+	code, body := doRequest(t, http.MethodGet, url, token, "")
+	if code != http.StatusOK {
+		t.Fatalf("leaderboard = %d %s", code, body)
+	}
+	if !strings.Contains(body, `"user_id":"admin"`) {
+		t.Errorf("leaderboard is missing the player: %s", body)
+	}
+}
+
+func TestLeaderboardCreditsTeamAsItWasWhenItWon(t *testing.T) {
+	useTestStore(t)
+
+	alice, bob := &User{ID: "a"}, &User{ID: "b"}
+	db.Users = append(db.Users, alice, bob)
+	theme := newTestTheme("t1", 3, 9)
+	theme.TeamMode = true
+	db.Themes = append(db.Themes, theme)
+
+	red, err := theme.newTeam("Red", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	card, err := theme.NewCard(alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	theme.setState(ThemeLive)
+	markItems(t, theme, card.Items[0]...)
+	publishWins(theme, nil, "")
+
+	// Bob joins once the game is over
+	theme.setState(ThemeFinished)
+	red.Members = append(red.Members, bob.ID)
+
+	for _, e := range leaderboard([]*Theme{theme}, defaultScoring) {
+		switch e.UserID {
+		case alice.ID:
+			if e.FirstPlaces != 1 || e.Bingos != 1 {
+				t.Errorf("alice = %+v, want the first place and bingo", e)
+			}
+		case bob.ID:
+			if e.FirstPlaces != 0 || e.Bingos != 0 {
+				t.Errorf("bob = %+v, want no credit for a win from before joining", e)
+			}
+		}
+	}
+}
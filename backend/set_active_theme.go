@@ -7,6 +7,7 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
+// setActiveThemeHandler changes the active theme of the default room.
 func setActiveThemeHandler(c echo.Context) error {
 	user := c.Get("user").(*User)
 	if !slices.Contains(db.AdminDiscordIDs, user.DiscordID) {
@@ -21,43 +22,23 @@ func setActiveThemeHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
 	}
 
-	if request.ThemeID == "" {
-		db.ActiveThemeID = ""
-		_ = saveDatabase()
-		broadcastUpdate("theme_changed", db.ActiveThemeID)
-		return c.JSON(http.StatusOK, map[string]any{
-			"message":         "Active theme cleared",
-			"active_theme_id": db.ActiveThemeID,
-		})
-	}
+	room, _ := findRoom(defaultRoomID)
 
-	// Verify theme exists and is not complete
-	var selectedTheme *Theme
-	for _, theme := range db.Themes {
-		if theme.ID == request.ThemeID {
-			selectedTheme = theme
-			break
-		}
+	// Verify theme exists, is not complete and is not running in another room
+	if err := checkRoomTheme(room, request.ThemeID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 
-	if selectedTheme == nil {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Theme not found"})
-	}
+	// Broadcasts the change to all connected clients
+	setRoomTheme(room, user, request.ThemeID)
 
-	if selectedTheme.IsComplete {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Cannot set a completed theme as active"})
-	}
-
-	db.ActiveThemeID = request.ThemeID
-	if err := saveDatabase(); err != nil {
-		c.Logger().Error("Error saving database:", err)
+	message := "Active theme updated"
+	if request.ThemeID == "" {
+		message = "Active theme cleared"
 	}
 
-	// Broadcast theme change to all connected clients
-	broadcastUpdate("theme_changed", db.ActiveThemeID)
-
 	return c.JSON(http.StatusOK, map[string]any{
-		"message":         "Active theme updated",
+		"message":         message,
 		"active_theme_id": db.ActiveThemeID,
 	})
 }
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// setRoomThemeHandler changes a room's active theme. An empty theme_id
+// clears it.
+func setRoomThemeHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		RoomID  string `param:"roomId"`
+		ThemeID string `json:"theme_id"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	room, ok := findRoom(req.RoomID)
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Room not found"})
+	}
+
+	if err := checkRoomTheme(room, req.ThemeID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	setRoomTheme(room, user, req.ThemeID)
+
+	return c.JSON(http.StatusOK, room)
+}
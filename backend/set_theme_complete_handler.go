@@ -27,16 +27,15 @@ func setThemeCompleteHandler(c echo.Context) error {
 	// Find and update the theme
 	for i, theme := range db.Themes {
 		if theme.ID == themeID {
-			// Don't allow marking the active theme as complete
-			if request.IsComplete && db.ActiveThemeID == themeID {
-				db.ActiveThemeID = ""
-				broadcastUpdate("theme_changed", db.ActiveThemeID)
-			}
-
-			db.Themes[i].IsComplete = request.IsComplete
-
-			if err := saveDatabase(); err != nil {
-				c.Logger().Error("Error saving database:", err)
+			// Completing finishes the game; reopening a finished one resumes it
+			if request.IsComplete != theme.IsComplete {
+				state := ThemeLive
+				if request.IsComplete {
+					state = ThemeFinished
+				}
+				if err := changeThemeState(theme, user, state); err != nil {
+					return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+				}
 			}
 
 			statusText := "incomplete"
@@ -44,8 +43,6 @@ func setThemeCompleteHandler(c echo.Context) error {
 				statusText = "complete"
 			}
 
-			broadcastUpdate("theme_updated", db.Themes[i])
-
 			return c.JSON(http.StatusOK, map[string]any{
 				"message": fmt.Sprintf("Theme marked as %s", statusText),
 				"theme":   db.Themes[i],
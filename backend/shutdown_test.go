@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+func TestCloseAllConnectionsFlushesAndSaysWhy(t *testing.T) {
+	useTestStore(t)
+
+	server := newTestServer(t)
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	waitForClients(t, 1)
+
+	broadcastUpdate("last_update", "bye")
+	closeAllConnections("server restarting", time.Now().Add(5*time.Second))
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("queued message lost on shutdown: %v", err)
+	}
+	if !strings.Contains(string(data), "last_update") {
+		t.Errorf("got %s, want the queued update", data)
+	}
+
+	_, _, err = conn.ReadMessage()
+	var closeErr *websocket.CloseError
+	if !errors.As(err, &closeErr) {
+		t.Fatalf("got %v, want a close frame", err)
+	}
+	if closeErr.Code != websocket.CloseServiceRestart || closeErr.Text != "server restarting" {
+		t.Errorf("close frame = %d %q, want %d %q", closeErr.Code, closeErr.Text, websocket.CloseServiceRestart, "server restarting")
+	}
+
+	connMutex.RLock()
+	defer connMutex.RUnlock()
+	if len(connections) != 0 {
+		t.Errorf("%d clients still registered", len(connections))
+	}
+}
+
+func TestPersisterCloseWritesPendingSaves(t *testing.T) {
+	p, path := newTestPersister(t, func(s Store) Store { return s })
+
+	if err := p.SaveUser(&User{ID: "u1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if d := loadSQLite(t, path); len(d.Users) != 1 {
+		t.Errorf("got %d users after closing, want the pending save written", len(d.Users))
+	}
+}
+
+func TestBackupScheduleStopsOnShutdown(t *testing.T) {
+	t.Setenv("BACKUP_DIR", t.TempDir())
+	useTestStore(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		runBackupSchedule(ctx, 10*time.Millisecond, 100)
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("backup schedule kept running after shutdown")
+	}
+
+	before, err := listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	after, err := listBackups()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(after) != len(before) {
+		t.Errorf("backups went from %d to %d after shutdown", len(before), len(after))
+	}
+}
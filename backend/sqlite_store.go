@@ -0,0 +1,598 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteSchema stores each entity as its JSON encoding next to the key
+// columns needed to look it up, so adding fields to a struct does not
+// require a table change.
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS settings (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS admin_discord_ids (
+	discord_id TEXT PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS users (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS themes (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS items (
+	id       TEXT NOT NULL,
+	theme_id TEXT NOT NULL,
+	position INTEGER NOT NULL,
+	data     TEXT NOT NULL,
+	PRIMARY KEY (theme_id, id)
+);
+CREATE TABLE IF NOT EXISTS cards (
+	id       TEXT PRIMARY KEY,
+	theme_id TEXT NOT NULL,
+	user_id  TEXT NOT NULL,
+	data     TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS cards_theme_id ON cards (theme_id);
+CREATE TABLE IF NOT EXISTS rooms (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS seasons (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS templates (
+	id   TEXT PRIMARY KEY,
+	data TEXT NOT NULL
+);
+`
+
+// execer is satisfied by both *sql.DB and *sql.Tx.
+type execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// sqliteStore persists the Database in an embedded SQLite file and only
+// writes the rows touched by each save.
+type sqliteStore struct {
+	conn *sql.DB
+	path string
+	// tx is the open transaction while Batch is running
+	tx *sql.Tx
+}
+
+func newSQLiteStore(path string) (*sqliteStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, err
+	}
+
+	conn, err := sql.Open("sqlite", "file:"+path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	// SQLite allows a single writer; one connection avoids SQLITE_BUSY between our own goroutines.
+	conn.SetMaxOpenConns(1)
+
+	if _, err := conn.Exec(sqliteSchema); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := upgradeItemsKey(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	return &sqliteStore{conn: conn, path: path}, nil
+}
+
+// upgradeItemsKey rebuilds an items table created when item IDs were the
+// primary key on their own. Item IDs only need to be unique within a theme.
+func upgradeItemsKey(conn *sql.DB) error {
+	var keyColumns int
+	if err := conn.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('items') WHERE pk > 0`).Scan(&keyColumns); err != nil {
+		return err
+	}
+	if keyColumns != 1 {
+		return nil
+	}
+
+	tx, err := conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DROP INDEX IF EXISTS items_theme_id`,
+		`ALTER TABLE items RENAME TO items_old`,
+		`CREATE TABLE items (
+			id       TEXT NOT NULL,
+			theme_id TEXT NOT NULL,
+			position INTEGER NOT NULL,
+			data     TEXT NOT NULL,
+			PRIMARY KEY (theme_id, id)
+		)`,
+		`INSERT INTO items (id, theme_id, position, data) SELECT id, theme_id, position, data FROM items_old`,
+		`DROP TABLE items_old`,
+	} {
+		if _, err := tx.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *sqliteStore) Load() (*Database, error) {
+	var initialized string
+	err := s.conn.QueryRow(`SELECT value FROM settings WHERE key = 'initialized'`).Scan(&initialized)
+	if errors.Is(err, sql.ErrNoRows) {
+		return s.initialize()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	db := newDatabase()
+
+	if err := s.conn.QueryRow(`SELECT value FROM settings WHERE key = 'active_theme_id'`).Scan(&db.ActiveThemeID); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	var schemaVersion string
+	if err := s.conn.QueryRow(`SELECT value FROM settings WHERE key = 'schema_version'`).Scan(&schemaVersion); err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+	if schemaVersion != "" {
+		if db.SchemaVersion, err = strconv.Atoi(schemaVersion); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := s.conn.Query(`SELECT discord_id FROM admin_discord_ids ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		db.AdminDiscordIDs = append(db.AdminDiscordIDs, id)
+	}
+	rows.Close()
+
+	if err := scanJSONRows(s.conn, `SELECT id, data FROM users ORDER BY rowid`, func(_ string, u *User) {
+		db.Users = append(db.Users, u)
+	}); err != nil {
+		return nil, err
+	}
+
+	themes := make(map[string]*Theme)
+	if err := scanJSONRows(s.conn, `SELECT id, data FROM themes ORDER BY rowid`, func(_ string, t *Theme) {
+		t.Items = []*Item{}
+		t.Cards = make(map[string]*Card)
+		themes[t.ID] = t
+		db.Themes = append(db.Themes, t)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := scanJSONRows(s.conn, `SELECT theme_id, data FROM items ORDER BY theme_id, position`, func(themeID string, item *Item) {
+		if theme, ok := themes[themeID]; ok {
+			theme.Items = append(theme.Items, item)
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := scanJSONRows(s.conn, `SELECT theme_id, data FROM cards ORDER BY rowid`, func(themeID string, card *Card) {
+		if theme, ok := themes[themeID]; ok {
+			theme.Cards[card.ID] = card
+		}
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := scanJSONRows(s.conn, `SELECT id, data FROM rooms ORDER BY rowid`, func(_ string, r *Room) {
+		db.Rooms = append(db.Rooms, r)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := scanJSONRows(s.conn, `SELECT id, data FROM seasons ORDER BY rowid`, func(_ string, season *Season) {
+		db.Seasons = append(db.Seasons, season)
+	}); err != nil {
+		return nil, err
+	}
+
+	if err := scanJSONRows(s.conn, `SELECT id, data FROM templates ORDER BY rowid`, func(_ string, template *ThemeTemplate) {
+		db.Templates = append(db.Templates, template)
+	}); err != nil {
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// initialize prepares a fresh SQLite file. If a JSON database from the
+// default store exists next to it, its contents are imported once.
+func (s *sqliteStore) initialize() (*Database, error) {
+	db := newDatabase()
+
+	legacyPath := legacyJSONPath(s.path)
+	if _, err := os.Stat(legacyPath); err == nil {
+		log.Printf("Importing existing JSON database from %s", legacyPath)
+		if db, err = newJSONStore(legacyPath, 0).Load(); err != nil {
+			return nil, err
+		}
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	return db, s.Replace(db)
+}
+
+// legacyJSONPath is where a JSON database imported into the SQLite file at
+// path would be.
+func legacyJSONPath(path string) string {
+	return filepath.Join(filepath.Dir(path), "database.json")
+}
+
+// snapshotSQLite copies the SQLite file at path, and its write-ahead log if
+// there is one, into dir and returns the copy's path. The files are only
+// read: even a read-only SQLite connection creates -wal and -shm files.
+func snapshotSQLite(path, dir string) (string, error) {
+	snapshot := filepath.Join(dir, filepath.Base(path))
+	if err := copyFile(path, snapshot); err != nil {
+		return "", err
+	}
+	if err := copyFile(path+"-wal", snapshot+"-wal"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return "", err
+	}
+
+	return snapshot, nil
+}
+
+// Replace overwrites every table with the contents of db in one transaction.
+func (s *sqliteStore) Replace(db *Database) error {
+	return s.update(func(tx *sql.Tx) error {
+		return replaceAll(tx, db)
+	})
+}
+
+func replaceAll(tx *sql.Tx, db *Database) error {
+	for _, table := range []string{"settings", "admin_discord_ids", "users", "themes", "items", "cards", "rooms", "seasons", "templates"} {
+		if _, err := tx.Exec(`DELETE FROM ` + table); err != nil {
+			return err
+		}
+	}
+
+	if err := saveAdminDiscordIDs(tx, db.AdminDiscordIDs); err != nil {
+		return err
+	}
+	if err := saveSetting(tx, "active_theme_id", db.ActiveThemeID); err != nil {
+		return err
+	}
+	if err := saveSetting(tx, "schema_version", strconv.Itoa(db.SchemaVersion)); err != nil {
+		return err
+	}
+	for _, user := range db.Users {
+		if err := saveUser(tx, user); err != nil {
+			return err
+		}
+	}
+	for _, theme := range db.Themes {
+		if err := saveTheme(tx, theme); err != nil {
+			return err
+		}
+		for _, card := range theme.Cards {
+			if err := saveCard(tx, card); err != nil {
+				return err
+			}
+		}
+	}
+	for _, room := range db.Rooms {
+		if err := saveRoom(tx, room); err != nil {
+			return err
+		}
+	}
+	for _, season := range db.Seasons {
+		if err := saveSeason(tx, season); err != nil {
+			return err
+		}
+	}
+	for _, template := range db.Templates {
+		if err := saveTemplate(tx, template); err != nil {
+			return err
+		}
+	}
+	return saveSetting(tx, "initialized", "1")
+}
+
+// update runs fn inside the open batch transaction, or in a transaction of
+// its own when no batch is running.
+func (s *sqliteStore) update(fn func(tx *sql.Tx) error) error {
+	if s.tx != nil {
+		return fn(s.tx)
+	}
+
+	tx, err := s.conn.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Batch commits every save made by fn in a single transaction.
+func (s *sqliteStore) Batch(fn func() error) error {
+	return s.update(func(tx *sql.Tx) error {
+		s.tx = tx
+		defer func() { s.tx = nil }()
+		return fn()
+	})
+}
+
+func (s *sqliteStore) SaveUser(user *User) error {
+	return s.update(func(tx *sql.Tx) error {
+		return saveUser(tx, user)
+	})
+}
+
+func (s *sqliteStore) SaveTheme(theme *Theme) error {
+	return s.update(func(tx *sql.Tx) error {
+		return saveTheme(tx, theme)
+	})
+}
+
+func (s *sqliteStore) DeleteTheme(themeID string) error {
+	return s.update(func(tx *sql.Tx) error {
+		for _, query := range []string{
+			`DELETE FROM cards WHERE theme_id = ?`,
+			`DELETE FROM items WHERE theme_id = ?`,
+			`DELETE FROM themes WHERE id = ?`,
+		} {
+			if _, err := tx.Exec(query, themeID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+func (s *sqliteStore) SaveItem(themeID string, item *Item) error {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return err
+	}
+
+	return s.update(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`UPDATE items SET data = ? WHERE theme_id = ? AND id = ?`, data, themeID, item.ID)
+		return err
+	})
+}
+
+func (s *sqliteStore) SaveCard(card *Card) error {
+	return s.update(func(tx *sql.Tx) error {
+		return saveCard(tx, card)
+	})
+}
+
+func (s *sqliteStore) SaveAdminDiscordIDs(ids []string) error {
+	return s.update(func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM admin_discord_ids`); err != nil {
+			return err
+		}
+		return saveAdminDiscordIDs(tx, ids)
+	})
+}
+
+func (s *sqliteStore) SaveActiveThemeID(themeID string) error {
+	return s.update(func(tx *sql.Tx) error {
+		return saveSetting(tx, "active_theme_id", themeID)
+	})
+}
+
+func (s *sqliteStore) SaveRoom(room *Room) error {
+	return s.update(func(tx *sql.Tx) error {
+		return saveRoom(tx, room)
+	})
+}
+
+func (s *sqliteStore) DeleteRoom(roomID string) error {
+	return s.update(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM rooms WHERE id = ?`, roomID)
+		return err
+	})
+}
+
+func (s *sqliteStore) SaveSeason(season *Season) error {
+	return s.update(func(tx *sql.Tx) error {
+		return saveSeason(tx, season)
+	})
+}
+
+func (s *sqliteStore) DeleteSeason(seasonID string) error {
+	return s.update(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM seasons WHERE id = ?`, seasonID)
+		return err
+	})
+}
+
+func (s *sqliteStore) SaveTemplate(template *ThemeTemplate) error {
+	return s.update(func(tx *sql.Tx) error {
+		return saveTemplate(tx, template)
+	})
+}
+
+func (s *sqliteStore) DeleteTemplate(templateID string) error {
+	return s.update(func(tx *sql.Tx) error {
+		_, err := tx.Exec(`DELETE FROM templates WHERE id = ?`, templateID)
+		return err
+	})
+}
+
+func (s *sqliteStore) Close() error {
+	return s.conn.Close()
+}
+
+func saveSetting(e execer, key, value string) error {
+	_, err := e.ExecContext(context.Background(),
+		`INSERT INTO settings (key, value) VALUES (?, ?) ON CONFLICT (key) DO UPDATE SET value = excluded.value`,
+		key, value)
+	return err
+}
+
+func saveAdminDiscordIDs(e execer, ids []string) error {
+	for _, id := range ids {
+		if _, err := e.ExecContext(context.Background(), `INSERT OR IGNORE INTO admin_discord_ids (discord_id) VALUES (?)`, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func saveUser(e execer, user *User) error {
+	data, err := json.Marshal(user)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.ExecContext(context.Background(),
+		`INSERT INTO users (id, data) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data`,
+		user.ID, data)
+	return err
+}
+
+// saveTheme writes the theme row and replaces its items. Cards are saved
+// separately with saveCard.
+func saveTheme(e execer, theme *Theme) error {
+	// Items and cards live in their own tables
+	row := *theme
+	row.Items = nil
+	row.Cards = nil
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return err
+	}
+
+	ctx := context.Background()
+	if _, err := e.ExecContext(ctx,
+		`INSERT INTO themes (id, data) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data`,
+		theme.ID, data); err != nil {
+		return err
+	}
+
+	if _, err := e.ExecContext(ctx, `DELETE FROM items WHERE theme_id = ?`, theme.ID); err != nil {
+		return err
+	}
+	for i, item := range theme.Items {
+		data, err := json.Marshal(item)
+		if err != nil {
+			return err
+		}
+		if _, err := e.ExecContext(ctx,
+			`INSERT INTO items (id, theme_id, position, data) VALUES (?, ?, ?, ?)`,
+			item.ID, theme.ID, i, data); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func saveCard(e execer, card *Card) error {
+	data, err := json.Marshal(card)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.ExecContext(context.Background(),
+		`INSERT INTO cards (id, theme_id, user_id, data) VALUES (?, ?, ?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data`,
+		card.ID, card.ThemeID, card.UserID, data)
+	return err
+}
+
+func saveRoom(e execer, room *Room) error {
+	data, err := json.Marshal(room)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.ExecContext(context.Background(),
+		`INSERT INTO rooms (id, data) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data`,
+		room.ID, data)
+	return err
+}
+
+func saveSeason(e execer, season *Season) error {
+	data, err := json.Marshal(season)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.ExecContext(context.Background(),
+		`INSERT INTO seasons (id, data) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data`,
+		season.ID, data)
+	return err
+}
+
+func saveTemplate(e execer, template *ThemeTemplate) error {
+	data, err := json.Marshal(template)
+	if err != nil {
+		return err
+	}
+
+	_, err = e.ExecContext(context.Background(),
+		`INSERT INTO templates (id, data) VALUES (?, ?) ON CONFLICT (id) DO UPDATE SET data = excluded.data`,
+		template.ID, data)
+	return err
+}
+
+// scanJSONRows runs a query selecting (key, data) pairs and decodes each
+// data column into a new T before handing it to fn.
+func scanJSONRows[T any](conn *sql.DB, query string, fn func(key string, v *T)) error {
+	rows, err := conn.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var key string
+		var data []byte
+		if err := rows.Scan(&key, &data); err != nil {
+			return err
+		}
+
+		v := new(T)
+		if err := json.Unmarshal(data, v); err != nil {
+			return err
+		}
+		fn(key, v)
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+)
+
+func openTestSQLiteStore(t *testing.T, path string) *sqliteStore {
+	t.Helper()
+
+	s, err := newSQLiteStore(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+
+	return s
+}
+
+func TestSQLiteStoreRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bingo.db")
+	s := openTestSQLiteStore(t, path)
+
+	d, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &User{ID: "u1", Username: "alice"}
+	theme := newTestTheme("t1", 5, 25)
+	d.Users = append(d.Users, user)
+	d.Themes = append(d.Themes, theme)
+
+	if err := s.SaveUser(user); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveTheme(theme); err != nil {
+		t.Fatal(err)
+	}
+
+	card, err := theme.NewCard(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveCard(card); err != nil {
+		t.Fatal(err)
+	}
+
+	theme.Items[3].Marked = true
+	if err := s.SaveItem(theme.ID, theme.Items[3]); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveActiveThemeID(theme.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveAdminDiscordIDs([]string{"d1", "d2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := openTestSQLiteStore(t, path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(loaded.Users) != 1 || loaded.Users[0].Username != "alice" {
+		t.Errorf("users = %+v", loaded.Users)
+	}
+	if loaded.ActiveThemeID != theme.ID {
+		t.Errorf("active theme = %q, want %q", loaded.ActiveThemeID, theme.ID)
+	}
+	if len(loaded.AdminDiscordIDs) != 2 {
+		t.Errorf("admin IDs = %v", loaded.AdminDiscordIDs)
+	}
+	if len(loaded.Themes) != 1 {
+		t.Fatalf("got %d themes, want 1", len(loaded.Themes))
+	}
+
+	got := loaded.Themes[0]
+	if len(got.Items) != 25 || got.Items[0].ID != "a" || !got.Items[3].Marked {
+		t.Errorf("items not restored in order with their marks")
+	}
+	if _, ok := got.Cards[card.ID]; !ok {
+		t.Errorf("card %s not restored", card.ID)
+	}
+}
+
+func TestSQLiteStoreItemIDsPerTheme(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bingo.db")
+	s := openTestSQLiteStore(t, path)
+
+	if _, err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Both themes use item IDs "a" through "i"
+	first, second := newTestTheme("t1", 3, 9), newTestTheme("t2", 3, 9)
+	for _, theme := range []*Theme{first, second} {
+		if err := s.SaveTheme(theme); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	second.Items[0].Marked = true
+	if err := s.SaveItem(second.ID, second.Items[0]); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.DeleteTheme(first.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := s.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Themes) != 1 || len(loaded.Themes[0].Items) != 9 || !loaded.Themes[0].Items[0].Marked {
+		t.Fatalf("second theme's items were not kept intact: %+v", loaded.Themes)
+	}
+}
+
+func TestSQLiteStoreUpgradesItemsKey(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bingo.db")
+
+	// An items table from before item IDs were keyed by theme
+	conn, err := sql.Open("sqlite", "file:"+path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		`CREATE TABLE items (id TEXT PRIMARY KEY, theme_id TEXT NOT NULL, position INTEGER NOT NULL, data TEXT NOT NULL)`,
+		`CREATE INDEX items_theme_id ON items (theme_id)`,
+		`INSERT INTO items VALUES ('a', 't1', 0, '{"id":"a","name":"kept"}')`,
+	} {
+		if _, err := conn.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	conn.Close()
+
+	s := openTestSQLiteStore(t, path)
+
+	var keyColumns int
+	if err := s.conn.QueryRow(`SELECT COUNT(*) FROM pragma_table_info('items') WHERE pk > 0`).Scan(&keyColumns); err != nil {
+		t.Fatal(err)
+	}
+	if keyColumns != 2 {
+		t.Fatalf("items has %d key columns, want 2", keyColumns)
+	}
+
+	var data string
+	if err := s.conn.QueryRow(`SELECT data FROM items WHERE theme_id = 't1' AND id = 'a'`).Scan(&data); err != nil {
+		t.Fatalf("existing item lost: %v", err)
+	}
+
+	if _, err := s.conn.Exec(`INSERT INTO items VALUES ('a', 't2', 0, '{}')`); err != nil {
+		t.Fatalf("item ID reused by another theme: %v", err)
+	}
+}
+
+func TestSQLiteStoreImportsJSONFromDatabaseDir(t *testing.T) {
+	dir := t.TempDir()
+
+	legacy := newJSONStore(filepath.Join(dir, "database.json"), 0)
+	legacyDB, err := legacy.Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	legacyDB.ActiveThemeID = "imported"
+	if err := legacy.SaveActiveThemeID(legacyDB.ActiveThemeID); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := openTestSQLiteStore(t, filepath.Join(dir, "custom.db")).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.ActiveThemeID != "imported" {
+		t.Fatalf("active theme = %q, want the JSON database next to DATABASE_PATH imported", d.ActiveThemeID)
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Store persists the bingo state. The Database returned by Load is the
+// in-memory working copy used by the handlers; after mutating it they call
+// the matching Save method so the backend can write only what changed.
+type Store interface {
+	Load() (*Database, error)
+	// Replace swaps the persisted state for db in its entirety.
+	Replace(db *Database) error
+	// Batch runs fn and persists every save it makes as a single write.
+	Batch(fn func() error) error
+	SaveUser(user *User) error
+	SaveTheme(theme *Theme) error
+	DeleteTheme(themeID string) error
+	SaveItem(themeID string, item *Item) error
+	SaveCard(card *Card) error
+	SaveAdminDiscordIDs(ids []string) error
+	SaveActiveThemeID(themeID string) error
+	SaveRoom(room *Room) error
+	DeleteRoom(roomID string) error
+	SaveSeason(season *Season) error
+	DeleteSeason(seasonID string) error
+	SaveTemplate(template *ThemeTemplate) error
+	DeleteTemplate(templateID string) error
+	Close() error
+}
+
+// newStore builds the Store selected by the STORAGE_DRIVER environment
+// variable ("json" or "sqlite"). DATABASE_PATH overrides the file location
+// and DATABASE_GENERATIONS sets how many previous JSON documents are kept.
+//
+// Unless SAVE_INTERVAL is 0, saves are batched in memory and flushed every
+// SAVE_INTERVAL or once SAVE_BATCH_SIZE changes are pending.
+func newStore() (Store, error) {
+	inner, err := newBackendStore()
+	if err != nil {
+		return nil, err
+	}
+
+	interval, err := time.ParseDuration(cmp.Or(os.Getenv("SAVE_INTERVAL"), "2s"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAVE_INTERVAL: %w", err)
+	}
+	if interval <= 0 {
+		return inner, nil
+	}
+
+	batchSize, err := strconv.Atoi(cmp.Or(os.Getenv("SAVE_BATCH_SIZE"), "100"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SAVE_BATCH_SIZE: %w", err)
+	}
+
+	p := newPersister(inner, interval, batchSize)
+	go p.run()
+
+	return p, nil
+}
+
+func newBackendStore() (Store, error) {
+	switch driver := cmp.Or(os.Getenv("STORAGE_DRIVER"), "json"); driver {
+	case "json":
+		generations, err := databaseGenerations()
+		if err != nil {
+			return nil, err
+		}
+		return newJSONStore(cmp.Or(os.Getenv("DATABASE_PATH"), "data/database.json"), generations), nil
+	case "sqlite":
+		return newSQLiteStore(cmp.Or(os.Getenv("DATABASE_PATH"), "data/bingo.db"))
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+func databaseGenerations() (int, error) {
+	generations, err := strconv.Atoi(cmp.Or(os.Getenv("DATABASE_GENERATIONS"), "3"))
+	if err != nil {
+		return 0, fmt.Errorf("invalid DATABASE_GENERATIONS: %w", err)
+	}
+	return generations, nil
+}
+
+// peekDatabase returns what the configured store would load, without
+// writing to the database or starting the persister. Loading normally
+// creates missing files, recovers corrupt ones and upgrades SQLite tables;
+// here a SQLite file is loaded from a read-only snapshot instead.
+func peekDatabase() (*Database, error) {
+	switch driver := cmp.Or(os.Getenv("STORAGE_DRIVER"), "json"); driver {
+	case "json":
+		generations, err := databaseGenerations()
+		if err != nil {
+			return nil, err
+		}
+		return peekJSON(cmp.Or(os.Getenv("DATABASE_PATH"), "data/database.json"), generations)
+	case "sqlite":
+		path := cmp.Or(os.Getenv("DATABASE_PATH"), "data/bingo.db")
+		if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+			// A new SQLite file starts from the JSON database next to it
+			return peekJSON(legacyJSONPath(path), 0)
+		}
+
+		dir, err := os.MkdirTemp("", "bingo-peek-*")
+		if err != nil {
+			return nil, err
+		}
+		defer os.RemoveAll(dir)
+
+		snapshot, err := snapshotSQLite(path, dir)
+		if err != nil {
+			return nil, err
+		}
+		s, err := newSQLiteStore(snapshot)
+		if err != nil {
+			return nil, err
+		}
+		defer s.Close()
+
+		return s.Load()
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", driver)
+	}
+}
+
+// peekJSON reads the JSON database at path, or the generation Load would
+// recover, treating a missing file as an empty database.
+func peekJSON(path string, generations int) (*Database, error) {
+	db, generation, err := newJSONStore(path, generations).read()
+	if errors.Is(err, fs.ErrNotExist) {
+		return newDatabase(), nil
+	}
+	if generation > 0 {
+		log.Printf("Database would be recovered from generation %d", generation)
+	}
+	return db, err
+}
+
+// newDatabase returns an empty Database with every collection initialized.
+func newDatabase() *Database {
+	return &Database{
+		Users:           []*User{},
+		AdminDiscordIDs: []string{},
+		Themes:          []*Theme{},
+		ActiveThemeID:   "",
+		Rooms:           []*Room{},
+		Seasons:         []*Season{},
+		Templates:       []*ThemeTemplate{},
+	}
+}
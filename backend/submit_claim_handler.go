@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"slices"
+	"time"
+
+	"github.com/labstack/echo/v4"
+)
+
+// submitClaimHandler lets a player call bingo on one of their cards. The
+// claim is queued for an admin to approve or reject. Without a card_id the
+// player's first winning card is claimed, or their first card if none won.
+func submitClaimHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		ThemeID string `param:"id"`
+		CardID  string `json:"card_id"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	if theme.State != ThemeLive {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Claims can only be made while the theme is live"})
+	}
+
+	holderID, err := theme.holderID(user)
+	if err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
+	}
+
+	cards := theme.userCards(holderID)
+	if req.CardID != "" {
+		cards = slices.DeleteFunc(cards, func(card *Card) bool {
+			return card.ID != req.CardID
+		})
+	}
+	if len(cards) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Card not found"})
+	}
+
+	card := cards[0]
+	if i := slices.IndexFunc(cards, func(card *Card) bool { return card.IsWinner }); i >= 0 {
+		card = cards[i]
+	}
+
+	if _, found := theme.pendingClaim(holderID); found {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "A claim on your cards is already waiting for review"})
+	}
+
+	if until, blocked := theme.claimPenalty(holderID, time.Now()); blocked {
+		return c.JSON(http.StatusTooManyRequests, map[string]string{
+			"error": fmt.Sprintf("You cannot claim again until %s", until.Format(time.RFC3339)),
+		})
+	}
+
+	claim := theme.newClaim(card, user)
+
+	if err := store.SaveTheme(theme); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventClaimSubmitted, user, theme.ID, claim.ID, nil, claim)
+
+	broadcastThemeUpdate(theme.ID, "claim_submitted", claim)
+
+	return c.JSON(http.StatusCreated, claim)
+}
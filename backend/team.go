@@ -0,0 +1,170 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Team is a squad of players in a team theme. Members share the team's
+// cards, and wins are credited to the team.
+type Team struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Members   []string  `json:"members"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func (t *Theme) getTeam(teamID string) (*Team, bool) {
+	for _, team := range t.Teams {
+		if team.ID == teamID {
+			return team, true
+		}
+	}
+	return nil, false
+}
+
+// userTeam returns the team the user plays for.
+func (t *Theme) userTeam(userID string) (*Team, bool) {
+	for _, team := range t.Teams {
+		if slices.Contains(team.Members, userID) {
+			return team, true
+		}
+	}
+	return nil, false
+}
+
+// holderID returns the ID that user's cards are dealt to: their team in a
+// team theme, otherwise the user themselves.
+func (t *Theme) holderID(user *User) (string, error) {
+	if !t.TeamMode {
+		return user.ID, nil
+	}
+	team, ok := t.userTeam(user.ID)
+	if !ok {
+		return "", errors.New("join a team before playing this theme")
+	}
+	return team.ID, nil
+}
+
+// newTeam creates a team with user as its first member.
+func (t *Theme) newTeam(name string, user *User) (*Team, error) {
+	if err := t.checkTeamChange(); err != nil {
+		return nil, err
+	}
+	if _, ok := t.userTeam(user.ID); ok {
+		return nil, errors.New("you are already on a team in this theme")
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, errors.New("team name is required")
+	}
+	if slices.ContainsFunc(t.Teams, func(team *Team) bool { return strings.EqualFold(team.Name, name) }) {
+		return nil, errors.New("a team with that name already exists")
+	}
+
+	team := &Team{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Members:   []string{user.ID},
+		CreatedAt: time.Now(),
+	}
+	t.Teams = append(t.Teams, team)
+
+	return team, nil
+}
+
+// joinTeam moves user onto team, leaving any team they were on.
+func (t *Theme) joinTeam(team *Team, user *User) error {
+	if err := t.checkTeamChange(); err != nil {
+		return err
+	}
+	if slices.Contains(team.Members, user.ID) {
+		return errors.New("you are already on this team")
+	}
+
+	t.leaveTeam(user)
+	team.Members = append(team.Members, user.ID)
+
+	return nil
+}
+
+// leaveTeam removes user from their team. A team left empty before it was
+// dealt a card is dropped.
+func (t *Theme) leaveTeam(user *User) (*Team, bool) {
+	team, ok := t.userTeam(user.ID)
+	if !ok {
+		return nil, false
+	}
+
+	team.Members = slices.DeleteFunc(team.Members, func(id string) bool { return id == user.ID })
+	if len(team.Members) == 0 && t.dealtCards(team.ID) == 0 {
+		t.Teams = slices.DeleteFunc(t.Teams, func(other *Team) bool { return other == team })
+	}
+
+	return team, true
+}
+
+// checkTeamChange reports why players cannot change teams: teams are
+// fixed once the game starts.
+func (t *Theme) checkTeamChange() error {
+	if !t.TeamMode {
+		return errors.New("theme is not played in teams")
+	}
+	if t.cardsLocked() {
+		return errors.New("the game has started, teams are locked")
+	}
+	return nil
+}
+
+// saveTeams saves, records and broadcasts a change to the theme's teams
+// made by actor. before is the theme's event state ahead of the change.
+// Callers hold dbMutex.
+func saveTeams(t *Theme, actor *User, before json.RawMessage) {
+	if err := store.SaveTheme(t); err != nil {
+		log.Println("Error saving database:", err)
+	}
+	recordEvent(EventThemeUpdated, actor, t.ID, t.ID, before, t)
+
+	broadcastThemeUpdate(t.ID, "teams_updated", map[string]any{
+		"theme_id": t.ID,
+		"teams":    t.Teams,
+	})
+}
+
+// TeamCards is a team together with its members and the cards it shares,
+// as shown in the admin card overview.
+type TeamCards struct {
+	*Team
+	Players []*User `json:"players"`
+	Cards   []*Card `json:"cards"`
+}
+
+// teamCardGroups groups the theme's cards by team.
+func teamCardGroups(t *Theme) []TeamCards {
+	groups := []TeamCards{}
+	for _, team := range t.Teams {
+		group := TeamCards{Team: team, Players: []*User{}, Cards: []*Card{}}
+		for _, user := range db.Users {
+			if slices.Contains(team.Members, user.ID) {
+				group.Players = append(group.Players, user)
+			}
+		}
+		for _, card := range t.Cards {
+			if card.TeamID == team.ID {
+				group.Cards = append(group.Cards, card)
+			}
+		}
+		slices.SortFunc(group.Cards, func(a, b *Card) int {
+			return a.Index - b.Index
+		})
+		groups = append(groups, group)
+	}
+	return groups
+}
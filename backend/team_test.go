@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestTeamsShareCardsAndWins(t *testing.T) {
+	useTestStore(t)
+
+	alice, bob, carol := &User{ID: "u1"}, &User{ID: "u2"}, &User{ID: "u3"}
+	db.Users = append(db.Users, alice, bob, carol)
+	theme := newTestTheme("t1", 3, 9)
+	theme.TeamMode = true
+	db.Themes = append(db.Themes, theme)
+
+	if _, err := theme.NewCard(alice); err == nil {
+		t.Fatal("dealt a card to a player without a team")
+	}
+
+	red, err := theme.newTeam("Red", alice)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := theme.newTeam("red", bob); err == nil {
+		t.Error("created a second team with the same name")
+	}
+	if err := theme.joinTeam(red, bob); err != nil {
+		t.Fatal(err)
+	}
+	blue, err := theme.newTeam("Blue", carol)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	card, err := theme.NewCard(bob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if card.TeamID != red.ID || len(theme.userCards(red.ID)) != 1 {
+		t.Fatalf("card %+v was not dealt to team %s", card, red.ID)
+	}
+	if holder, _ := theme.holderID(alice); holder != red.ID {
+		t.Errorf("alice holds cards for %q, want her team %s", holder, red.ID)
+	}
+	if v := theme.verifyCard(card); !v.Matches {
+		t.Errorf("team card does not verify: %s", v.Reason)
+	}
+
+	theme.leaveTeam(carol)
+	if _, ok := theme.getTeam(blue.ID); ok {
+		t.Error("empty team without cards was kept")
+	}
+
+	theme.setState(ThemeLive)
+	markItems(t, theme, card.Items[0]...)
+	won := publishWins(theme, nil, "")
+	if len(won) != 1 || won[0].TeamID != red.ID {
+		t.Fatalf("wins = %+v, want one for team %s", won, red.ID)
+	}
+	if err := theme.checkTeamChange(); err == nil {
+		t.Error("players can change teams during the game")
+	}
+
+	groups := teamCardGroups(theme)
+	if len(groups) != 1 || len(groups[0].Players) != 2 || len(groups[0].Cards) != 1 {
+		t.Errorf("card groups = %+v, want red with two players and one card", groups)
+	}
+	if err := validateDatabase(db); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestTeamHandlers(t *testing.T) {
+	useTestStore(t)
+
+	alice, bob := &User{ID: "u1"}, &User{ID: "u2"}
+	db.Users = append(db.Users, alice, bob)
+	theme := newTestTheme("t1", 3, 9)
+	theme.TeamMode = true
+	db.Themes = append(db.Themes, theme)
+
+	server := newTestServer(t)
+	teamsURL := server.URL + "/api/themes/t1/teams"
+	aliceToken, bobToken := testToken(t, alice.ID), testToken(t, bob.ID)
+
+	code, body := doRequest(t, http.MethodPost, teamsURL, aliceToken, `{"name": "Red"}`)
+	if code != http.StatusCreated {
+		t.Fatalf("create team = %d %s", code, body)
+	}
+	var team Team
+	if err := json.Unmarshal([]byte(body), &team); err != nil {
+		t.Fatal(err)
+	}
+
+	if code, body := doRequest(t, http.MethodPost, teamsURL+"/"+team.ID+"/join", bobToken, ""); code != http.StatusOK {
+		t.Fatalf("join team = %d %s", code, body)
+	}
+	if red, _ := theme.getTeam(team.ID); len(red.Members) != 2 {
+		t.Errorf("team members = %v, want alice and bob", red.Members)
+	}
+
+	theme.setState(ThemeLive)
+	if code, _ := doRequest(t, http.MethodPost, teamsURL+"/leave", bobToken, ""); code != http.StatusConflict {
+		t.Errorf("leaving a team during the game = %d, want %d", code, http.StatusConflict)
+	}
+}
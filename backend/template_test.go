@@ -0,0 +1,154 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestThemeTemplates(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "admin-discord"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	source := newTestTheme("src", 3, 9)
+	source.Name = "Source"
+	source.MaxRerolls = 2
+	source.setState(ThemeLive)
+	source.Cards["c1"] = &Card{ID: "c1", UserID: admin.ID, ThemeID: source.ID}
+	markItems(t, source, "a")
+	db.Themes = append(db.Themes, source)
+
+	server := newTestServer(t)
+	token := testToken(t, admin.ID)
+	post := func(path, body string, out any) int {
+		t.Helper()
+		code, resp := doRequest(t, http.MethodPost, server.URL+"/api/admin"+path, token, body)
+		if out != nil && code < http.StatusBadRequest {
+			if err := json.Unmarshal([]byte(resp), out); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return code
+	}
+
+	var clone Theme
+	if code := post("/themes/src/clone", "", &clone); code != http.StatusCreated {
+		t.Fatalf("clone = %d", code)
+	}
+	if clone.Name != "Source (copy)" || clone.State != ThemeDraft || clone.MaxRerolls != 2 {
+		t.Errorf("clone = %s in %s with %d rerolls, want a draft copy with 2 rerolls", clone.Name, clone.State, clone.MaxRerolls)
+	}
+	if len(clone.Cards) != 0 || clone.Items[0].Marked || clone.Items[0].ID == "a" || clone.Items[0].Name != "a" {
+		t.Errorf("clone kept cards or marks, or reused item IDs: %+v", clone.Items[0])
+	}
+	if code := post("/themes/src/clone", `{"name": "Bigger", "grid_size": 4}`, nil); code != http.StatusBadRequest {
+		t.Errorf("clone without enough items for its grid = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	var template ThemeTemplate
+	if code := post("/themes/src/template", `{"name": "Template"}`, &template); code != http.StatusCreated {
+		t.Fatalf("save template = %d", code)
+	}
+	if template.Name != "Template" || len(template.Items) != 9 {
+		t.Errorf("template = %s with %d items, want Template with 9", template.Name, len(template.Items))
+	}
+	if code := post("/templates", `{"description": "no name or items"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("template without items = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	var theme Theme
+	body := `{"name": "From template", "state": "open", "max_rerolls": 5}`
+	if code := post("/templates/"+template.ID+"/instantiate", body, &theme); code != http.StatusCreated {
+		t.Fatalf("instantiate = %d", code)
+	}
+	if theme.Name != "From template" || theme.State != ThemeOpen || theme.MaxRerolls != 5 {
+		t.Errorf("theme = %s in %s with %d rerolls, want the overrides applied", theme.Name, theme.State, theme.MaxRerolls)
+	}
+	if code := post("/templates/"+template.ID+"/instantiate", `{"state": "live"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("instantiating straight to live = %d, want %d", code, http.StatusBadRequest)
+	}
+
+	code, _ := doRequest(t, http.MethodDelete, server.URL+"/api/admin/templates/"+template.ID, token, "")
+	if code != http.StatusOK || len(db.Templates) != 0 {
+		t.Errorf("delete template = %d leaving %d templates", code, len(db.Templates))
+	}
+}
+
+func TestSQLiteStoreTemplates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bingo.db")
+	s := openTestSQLiteStore(t, path)
+	if _, err := s.Load(); err != nil {
+		t.Fatal(err)
+	}
+
+	source := newTestTheme("src", 3, 9)
+	source.MaxRerolls = 2
+	kept := &ThemeTemplate{ID: "kept", Name: "Kept", Items: source.itemSpecs(), ThemeSettings: source.settings()}
+	deleted := &ThemeTemplate{ID: "deleted", Name: "Deleted", Items: source.itemSpecs()}
+	for _, template := range []*ThemeTemplate{kept, deleted} {
+		if err := s.SaveTemplate(template); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.DeleteTemplate(deleted.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	loaded, err := openTestSQLiteStore(t, path).Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(loaded.Templates) != 1 || len(loaded.Templates[0].Items) != 9 || loaded.Templates[0].MaxRerolls != 2 {
+		t.Errorf("templates = %+v, want only kept with its items and settings", loaded.Templates)
+	}
+}
+
+func TestCloneReplacesOverriddenListsWhole(t *testing.T) {
+	useTestStore(t)
+
+	admin := &User{ID: "admin", DiscordID: "admin-discord"}
+	db.Users = append(db.Users, admin)
+	db.AdminDiscordIDs = []string{admin.DiscordID}
+	source := newTestTheme("src", 3, 9)
+	for _, item := range source.Items {
+		item.Weight = 5
+		item.Category = "rare"
+	}
+	source.WinPatterns = []WinPattern{{Name: "Corners", Type: PatternFourCorners}}
+	db.Themes = append(db.Themes, source)
+
+	server := newTestServer(t)
+	body := `{
+		"items": [{"name": "1"}, {"name": "2"}, {"name": "3"}, {"name": "4"}, {"name": "5"}, {"name": "6"}, {"name": "7"}, {"name": "8"}],
+		"win_patterns": [{"type": "blackout"}]
+	}`
+	code, resp := doRequest(t, http.MethodPost, server.URL+"/api/admin/themes/src/clone", testToken(t, admin.ID), body)
+	if code != http.StatusCreated {
+		t.Fatalf("clone = %d %s", code, resp)
+	}
+	var clone Theme
+	if err := json.Unmarshal([]byte(resp), &clone); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(clone.Items) != 8 {
+		t.Fatalf("clone has %d items, want the 8 sent", len(clone.Items))
+	}
+	for _, item := range clone.Items {
+		if item.Weight == 5 || item.Category != "" {
+			t.Errorf("item %s kept the source's weight %v and category %q", item.Name, item.Weight, item.Category)
+		}
+	}
+	if len(clone.WinPatterns) != 1 || clone.WinPatterns[0] != (WinPattern{Type: PatternBlackout}) {
+		t.Errorf("patterns = %+v, want only the blackout sent", clone.WinPatterns)
+	}
+	if clone.GridSize != 3 {
+		t.Errorf("grid size = %d, want the source's 3", clone.GridSize)
+	}
+}
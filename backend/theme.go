@@ -2,30 +2,113 @@ package main
 
 import (
 	"fmt"
-	"math/rand/v2"
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+const (
+	minGridSize     = 3
+	maxGridSize     = 7
+	defaultGridSize = 5
+)
+
+// Marking modes. In called mode marking an item marks it on every card. In
+// daub mode the admin calls items and players daub them on their own cards.
+const (
+	MarkingCalled = "called"
+	MarkingDaub   = "daub"
+)
+
 type Theme struct {
 	ID          string           `json:"id"`
 	Name        string           `json:"name"`
 	Description string           `json:"description"`
 	Items       []*Item          `json:"items"`
 	IsComplete  bool             `json:"is_complete"`
+	State       string           `json:"state"`
+	StartsAt    *time.Time       `json:"starts_at,omitempty"`
+	EndsAt      *time.Time       `json:"ends_at,omitempty"`
 	Cards       map[string]*Card `json:"cards"`
 	CreatedAt   time.Time        `json:"created_at"`
+	Seed        Seed             `json:"seed,omitempty"` // Makes card generation reproducible
+	GridSize    int              `json:"grid_size"`
+	WinPatterns []WinPattern     `json:"win_patterns,omitempty"`
+	MarkingMode string           `json:"marking_mode,omitempty"`
+	LayoutRules []LayoutRule     `json:"layout_rules,omitempty"`
+	// MaxCardsPerPlayer and MaxRerolls limit the cards a player can hold
+	// and replace before the game starts
+	MaxCardsPerPlayer int `json:"max_cards_per_player,omitempty"`
+	MaxRerolls        int `json:"max_rerolls,omitempty"`
+	// TeamMode deals cards to Teams instead of individual players
+	TeamMode bool    `json:"team_mode,omitempty"`
+	Teams    []*Team `json:"teams,omitempty"`
+	// MinCardDistance is how many cells any two cards must differ in
+	MinCardDistance int `json:"min_card_distance,omitempty"`
+	// Wins lists every win in the order it happened
+	Wins   []*Win   `json:"wins,omitempty"`
+	Claims []*Claim `json:"claims,omitempty"`
+	// ClaimPenaltySeconds is how long a rejected claim can bar the player
+	// from claiming again
+	ClaimPenaltySeconds int `json:"claim_penalty_seconds,omitempty"`
+	// Caller is set while the server draws items for this theme
+	Caller *Caller `json:"caller,omitempty"`
+}
+
+// validateGridSize checks that cards of the given size can be played.
+func validateGridSize(size int) error {
+	if size < minGridSize || size > maxGridSize {
+		return fmt.Errorf("grid size must be between %d and %d", minGridSize, maxGridSize)
+	}
+	return nil
+}
+
+// freeSpace returns the position of the free space on a grid of the given
+// size. Odd sizes have a free center square; even sizes have none.
+func freeSpace(size int) (row, col int, ok bool) {
+	if size%2 == 0 {
+		return 0, 0, false
+	}
+	return size / 2, size / 2, true
+}
+
+// requiredItems returns how many items a card of the given size draws.
+func requiredItems(size int) int {
+	n := size * size
+	if _, _, ok := freeSpace(size); ok {
+		n--
+	}
+	return n
+}
+
+// gridSize returns the size of the cards generated for this theme. Themes
+// created before grid sizes were configurable use 5x5.
+func (t *Theme) gridSize() int {
+	if t.GridSize == 0 {
+		return defaultGridSize
+	}
+	return t.GridSize
+}
+
+// validateMarkingMode checks that mode is a known marking mode. An empty
+// mode means called.
+func validateMarkingMode(mode string) error {
+	switch mode {
+	case "", MarkingCalled, MarkingDaub:
+		return nil
+	}
+	return fmt.Errorf("unknown marking mode %q", mode)
+}
+
+// daubing reports whether players mark their own cards.
+func (t *Theme) daubing() bool {
+	return t.MarkingMode == MarkingDaub
 }
 
 // Get theme by ID
 func getThemeByID(themeID string) (*Theme, bool) {
-	for _, theme := range db.Themes {
-		if theme.ID == themeID {
-			return theme, true
-		}
-	}
-	return nil, false
+	return findTheme(db, themeID)
 }
 
 func (t *Theme) GetItem(itemID string) (*Item, bool) {
@@ -38,61 +121,149 @@ func (t *Theme) GetItem(itemID string) (*Item, bool) {
 }
 
 func (t *Theme) NewCard(user *User) (*Card, error) {
-	if t.IsComplete {
-		return nil, fmt.Errorf("cannot generate card from a completed theme")
+	if t.State != ThemeOpen {
+		return nil, fmt.Errorf("cards can only be dealt while the theme is open, it is %s", t.State)
 	}
 
-	if len(t.Items) < 25 {
-		return nil, fmt.Errorf("theme has insufficient items: need at least 25, have %d", len(t.Items))
+	size := t.gridSize()
+	required := requiredItems(size)
+	if len(t.Items) < required {
+		return nil, fmt.Errorf("theme has insufficient items: need at least %d, have %d", required, len(t.Items))
+	}
+
+	holderID, err := t.holderID(user)
+	if err != nil {
+		return nil, err
+	}
+
+	// Cards are numbered in the order the user or team was dealt them,
+	// rerolled cards included
+	index := t.dealtCards(holderID)
+	_, digest := t.itemPool()
+
+	grid, attempt, err := t.dealGrid(holderID, index, size)
+	if err != nil {
+		return nil, err
 	}
 
-	// Create a new bingo card
 	card := &Card{
-		ID:        uuid.New().String(),
-		UserID:    user.ID,
-		ThemeID:   t.ID,
-		Items:     make([][]string, 5),
-		CreatedAt: time.Now(),
-		IsWinner:  false,
-	}
-
-	// Shuffle and select 25 items
-	shuffled := make([]*Item, len(t.Items))
-	copy(shuffled, t.Items)
-	rand.Shuffle(len(shuffled), func(i, j int) {
-		shuffled[i], shuffled[j] = shuffled[j], shuffled[i]
+		ID:         uuid.New().String(),
+		UserID:     user.ID,
+		ThemeID:    t.ID,
+		Items:      grid,
+		CreatedAt:  time.Now(),
+		IsWinner:   false,
+		Index:      index,
+		Attempt:    attempt,
+		PoolDigest: digest,
+	}
+	if t.TeamMode {
+		card.TeamID = holderID
+	}
+	card.checkBingo(t)
+
+	if t.Cards == nil {
+		t.Cards = make(map[string]*Card)
+	}
+	t.Cards[card.ID] = card
+
+	return card, nil
+}
+
+// userCards returns the cards held by the user or team with the given ID
+// that are still in play, in the order they were dealt.
+func (t *Theme) userCards(holderID string) []*Card {
+	var cards []*Card
+	for _, card := range t.Cards {
+		if card.holderID() == holderID && card.active() {
+			cards = append(cards, card)
+		}
+	}
+	slices.SortFunc(cards, func(a, b *Card) int {
+		return a.Index - b.Index
 	})
-	selected := shuffled[:25]
+	return cards
+}
+
+// dealtCards counts every card the user or team has been dealt, rerolled
+// ones included.
+func (t *Theme) dealtCards(holderID string) int {
+	n := 0
+	for _, card := range t.Cards {
+		if card.holderID() == holderID {
+			n++
+		}
+	}
+	return n
+}
 
-	// Fill the 5x5 grid
-	for i := range card.Items {
-		card.Items[i] = make([]string, 5)
-		for j := range card.Items[i] {
-			card.Items[i][j] = selected[i*5+j].ID
+// rerolls counts how many of the user's or team's cards have been
+// rerolled.
+func (t *Theme) rerolls(holderID string) int {
+	n := 0
+	for _, card := range t.Cards {
+		if card.holderID() == holderID && !card.active() {
+			n++
 		}
 	}
+	return n
+}
 
-	// Free space in the middle
-	card.Items[2][2] = "FREE_SPACE"
+// maxCardsPerPlayer returns how many cards a player, or a team, may hold
+// at once.
+func (t *Theme) maxCardsPerPlayer() int {
+	return max(t.MaxCardsPerPlayer, 1)
+}
 
-	if t.Cards == nil {
-		t.Cards = make(map[string]*Card)
+// cardsLocked reports whether the game has started, after which players
+// can no longer add or reroll cards.
+func (t *Theme) cardsLocked() bool {
+	if t.State != ThemeOpen || t.Caller != nil {
+		return true
 	}
-	t.Cards[user.ID] = card
+	for _, item := range t.Items {
+		if item.Marked {
+			return true
+		}
+	}
+	return false
+}
 
-	return card, saveDatabase()
+// rerollCard retires card and deals the user a replacement.
+func (t *Theme) rerollCard(card *Card, user *User) (*Card, error) {
+	now := time.Now()
+	card.RetiredAt = &now
+
+	replacement, err := t.NewCard(user)
+	if err != nil {
+		card.RetiredAt = nil
+		return nil, err
+	}
+
+	return replacement, nil
 }
 
-func (t *Theme) checkForWinners() []*Card {
-	var winners []*Card
+// checkForWinners re-evaluates every card and returns the winning cards
+// along with the cards whose winner status or distance to a win changed
+// and need saving.
+func (t *Theme) checkForWinners() (winners, changed []*Card) {
 	// Check all cards for winners
 	for _, card := range t.Cards {
+		if !card.active() {
+			continue
+		}
+		wasWinner, hadPatterns := card.IsWinner, card.Patterns
+		hadRemaining, hadNeeded := card.Remaining, card.NeededItems
 		card.checkBingo(t)
+		if card.IsWinner != wasWinner || !slices.Equal(card.Patterns, hadPatterns) ||
+			card.Remaining != hadRemaining || !slices.Equal(card.NeededItems, hadNeeded) {
+			changed = append(changed, card)
+		}
 		if card.IsWinner {
 			winners = append(winners, card)
 		}
 	}
-	return winners
+	return winners, changed
 }
 
 // Set active theme (admin only)
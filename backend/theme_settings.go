@@ -0,0 +1,134 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ThemeSettings are the rules of a theme that carry over when it is cloned
+// or saved as a template.
+type ThemeSettings struct {
+	GridSize            int          `json:"grid_size"`
+	WinPatterns         []WinPattern `json:"win_patterns,omitempty"`
+	MarkingMode         string       `json:"marking_mode,omitempty"`
+	ClaimPenaltySeconds int          `json:"claim_penalty_seconds,omitempty"`
+	MinCardDistance     int          `json:"min_card_distance,omitempty"`
+	LayoutRules         []LayoutRule `json:"layout_rules,omitempty"`
+	MaxCardsPerPlayer   int          `json:"max_cards_per_player,omitempty"`
+	MaxRerolls          int          `json:"max_rerolls,omitempty"`
+	TeamMode            bool         `json:"team_mode,omitempty"`
+}
+
+func (t *Theme) settings() ThemeSettings {
+	return ThemeSettings{
+		GridSize:            t.gridSize(),
+		WinPatterns:         slices.Clone(t.WinPatterns),
+		MarkingMode:         t.MarkingMode,
+		ClaimPenaltySeconds: t.ClaimPenaltySeconds,
+		MinCardDistance:     t.MinCardDistance,
+		LayoutRules:         slices.Clone(t.LayoutRules),
+		MaxCardsPerPlayer:   t.MaxCardsPerPlayer,
+		MaxRerolls:          t.MaxRerolls,
+		TeamMode:            t.TeamMode,
+	}
+}
+
+// itemSpecs returns the theme's items as they would be sent to create it.
+func (t *Theme) itemSpecs() []itemSpec {
+	specs := make([]itemSpec, len(t.Items))
+	for i, item := range t.Items {
+		specs[i] = itemSpec{Name: item.Name, Weight: item.Weight, Category: item.Category}
+	}
+	return specs
+}
+
+// validate checks that a theme with these settings and items can be
+// played.
+func (s ThemeSettings) validate(items []*Item) error {
+	if err := validateGridSize(s.GridSize); err != nil {
+		return err
+	}
+	if err := validateWinPatterns(s.WinPatterns, s.GridSize); err != nil {
+		return err
+	}
+	if err := validateMarkingMode(s.MarkingMode); err != nil {
+		return err
+	}
+	if err := validateMinCardDistance(s.MinCardDistance, s.GridSize); err != nil {
+		return err
+	}
+	if s.MaxCardsPerPlayer < 0 || s.MaxRerolls < 0 {
+		return errors.New("card and reroll limits cannot be negative")
+	}
+	if s.ClaimPenaltySeconds < 0 {
+		return errors.New("claim penalty cannot be negative")
+	}
+	if required := requiredItems(s.GridSize); len(items) < required {
+		return fmt.Errorf("theme must have at least %d items", required)
+	}
+	if err := validateLayoutRules(s.LayoutRules, s.GridSize); err != nil {
+		return err
+	}
+	return validateItemWeights(items)
+}
+
+// newTheme builds a theme in state from item specs, giving every item a new
+// ID. New themes must be draft or open.
+func newTheme(name, description string, specs []itemSpec, settings ThemeSettings, state string) (*Theme, error) {
+	if state != ThemeDraft && state != ThemeOpen {
+		return nil, errors.New("new themes must be draft or open")
+	}
+
+	items := make([]*Item, len(specs))
+	for i, spec := range specs {
+		items[i] = &Item{
+			ID:       uuid.New().String(),
+			Name:     spec.Name,
+			Weight:   spec.Weight,
+			Category: spec.Category,
+		}
+	}
+
+	if err := settings.validate(items); err != nil {
+		return nil, err
+	}
+
+	theme := &Theme{
+		ID:                  uuid.New().String(),
+		Name:                name,
+		Description:         description,
+		Items:               items,
+		Cards:               make(map[string]*Card),
+		CreatedAt:           time.Now(),
+		Seed:                newThemeSeed(),
+		GridSize:            settings.GridSize,
+		WinPatterns:         withGridSize(settings.WinPatterns, settings.GridSize),
+		MarkingMode:         settings.MarkingMode,
+		ClaimPenaltySeconds: settings.ClaimPenaltySeconds,
+		MinCardDistance:     settings.MinCardDistance,
+		LayoutRules:         settings.LayoutRules,
+		MaxCardsPerPlayer:   settings.MaxCardsPerPlayer,
+		MaxRerolls:          settings.MaxRerolls,
+		TeamMode:            settings.TeamMode,
+	}
+	theme.setState(state)
+
+	return theme, nil
+}
+
+// addTheme adds a new theme to the database, then saves, records and
+// broadcasts it. Callers hold dbMutex.
+func addTheme(theme *Theme, actor *User) {
+	db.Themes = append(db.Themes, theme)
+	if err := store.SaveTheme(theme); err != nil {
+		log.Println("Error saving database:", err)
+	}
+	recordEvent(EventThemeCreated, actor, theme.ID, theme.ID, nil, theme)
+
+	broadcastUpdate("theme_created", forPlayers(theme))
+}
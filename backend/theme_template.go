@@ -0,0 +1,117 @@
+package main
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ThemeTemplate is a saved item list and set of rules that new themes can
+// be created from. Name is also the default name of those themes.
+type ThemeTemplate struct {
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Items       []itemSpec `json:"items"`
+	ThemeSettings
+	CreatedAt time.Time `json:"created_at"`
+}
+
+func getTemplateByID(templateID string) (*ThemeTemplate, bool) {
+	for _, template := range db.Templates {
+		if template.ID == templateID {
+			return template, true
+		}
+	}
+	return nil, false
+}
+
+// themeFields are the values a theme or template is created from.
+type themeFields struct {
+	Name        string
+	Description string
+	Items       []itemSpec
+	ThemeSettings
+	State string
+}
+
+// themeOverrides is the body accepted when creating a theme or template
+// from another one. Fields left out of the request are nil and keep the
+// source's values; those sent replace them whole.
+type themeOverrides struct {
+	Name                *string       `json:"name"`
+	Description         *string       `json:"description"`
+	Items               *[]itemSpec   `json:"items"`
+	GridSize            *int          `json:"grid_size"`
+	WinPatterns         *[]WinPattern `json:"win_patterns"`
+	MarkingMode         *string       `json:"marking_mode"`
+	ClaimPenaltySeconds *int          `json:"claim_penalty_seconds"`
+	MinCardDistance     *int          `json:"min_card_distance"`
+	LayoutRules         *[]LayoutRule `json:"layout_rules"`
+	MaxCardsPerPlayer   *int          `json:"max_cards_per_player"`
+	MaxRerolls          *int          `json:"max_rerolls"`
+	TeamMode            *bool         `json:"team_mode"`
+	State               *string       `json:"state"`
+}
+
+// apply replaces the fields of f that the request sent.
+func (o themeOverrides) apply(f *themeFields) {
+	setIfSent(&f.Name, o.Name)
+	setIfSent(&f.Description, o.Description)
+	setIfSent(&f.Items, o.Items)
+	setIfSent(&f.GridSize, o.GridSize)
+	setIfSent(&f.WinPatterns, o.WinPatterns)
+	setIfSent(&f.MarkingMode, o.MarkingMode)
+	setIfSent(&f.ClaimPenaltySeconds, o.ClaimPenaltySeconds)
+	setIfSent(&f.MinCardDistance, o.MinCardDistance)
+	setIfSent(&f.LayoutRules, o.LayoutRules)
+	setIfSent(&f.MaxCardsPerPlayer, o.MaxCardsPerPlayer)
+	setIfSent(&f.MaxRerolls, o.MaxRerolls)
+	setIfSent(&f.TeamMode, o.TeamMode)
+	setIfSent(&f.State, o.State)
+}
+
+func setIfSent[T any](field *T, sent *T) {
+	if sent != nil {
+		*field = *sent
+	}
+}
+
+// newTemplate builds a template from f, checking that themes created from
+// it would be valid.
+func newTemplate(f themeFields) (*ThemeTemplate, error) {
+	name := strings.TrimSpace(f.Name)
+	if name == "" {
+		return nil, errors.New("template name is required")
+	}
+
+	items := make([]*Item, len(f.Items))
+	for i, spec := range f.Items {
+		items[i] = &Item{Name: spec.Name, Weight: spec.Weight, Category: spec.Category}
+	}
+	if err := f.ThemeSettings.validate(items); err != nil {
+		return nil, err
+	}
+
+	return &ThemeTemplate{
+		ID:            uuid.New().String(),
+		Name:          name,
+		Description:   f.Description,
+		Items:         f.Items,
+		ThemeSettings: f.ThemeSettings,
+		CreatedAt:     time.Now(),
+	}, nil
+}
+
+// addTemplate adds a new template to the database, then saves and records
+// it. Callers hold dbMutex.
+func addTemplate(template *ThemeTemplate, actor *User) {
+	db.Templates = append(db.Templates, template)
+	if err := store.SaveTemplate(template); err != nil {
+		log.Println("Error saving database:", err)
+	}
+	recordEvent(EventTemplateCreated, actor, "", template.ID, nil, template)
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestRerollCard(t *testing.T) {
+	theme := newTestTheme("t1", 3, 20)
+	user := &User{ID: "u1"}
+
+	first, err := theme.NewCard(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := theme.NewCard(user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.Index != 1 {
+		t.Errorf("second card has index %d, want 1", second.Index)
+	}
+
+	replacement, err := theme.rerollCard(first, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.active() {
+		t.Error("rerolled card is still active")
+	}
+	if replacement.Index != 2 {
+		t.Errorf("replacement has index %d, want 2", replacement.Index)
+	}
+	if got := theme.rerolls(user.ID); got != 1 {
+		t.Errorf("rerolls = %d, want 1", got)
+	}
+	if cards := theme.userCards(user.ID); len(cards) != 2 || cards[0] != second || cards[1] != replacement {
+		t.Errorf("user holds %v, want the second card and its replacement", cards)
+	}
+	if v := theme.verifyCard(replacement); !v.Matches {
+		t.Errorf("replacement does not verify: %s", v.Reason)
+	}
+
+	// A retired card cannot win, even when its items are called
+	theme.setState(ThemeLive)
+	markItems(t, theme, first.Items[0]...)
+	markItems(t, theme, second.Items[0]...)
+	won, _, _ := theme.updateWins("", "", second.CreatedAt)
+	for _, win := range won {
+		if win.CardID == first.ID {
+			t.Error("retired card won")
+		}
+	}
+	if !theme.cardsLocked() {
+		t.Error("cards are not locked once the game is live")
+	}
+}
+
+func TestAddAndRerollCardHandlers(t *testing.T) {
+	useTestStore(t)
+
+	user := &User{ID: "u1"}
+	db.Users = append(db.Users, user)
+	theme := newTestTheme("t1", 3, 20)
+	theme.MaxCardsPerPlayer = 2
+	theme.MaxRerolls = 1
+	db.Themes = append(db.Themes, theme)
+
+	server := newTestServer(t)
+	token := testToken(t, user.ID)
+	cardsURL := server.URL + "/api/themes/t1/cards"
+
+	var card Card
+	for range 2 {
+		code, body := doRequest(t, http.MethodPost, cardsURL, token, "")
+		if code != http.StatusCreated {
+			t.Fatalf("add card = %d %s", code, body)
+		}
+		if err := json.Unmarshal([]byte(body), &card); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if code, _ := doRequest(t, http.MethodPost, cardsURL, token, ""); code != http.StatusConflict {
+		t.Errorf("card over the limit = %d, want %d", code, http.StatusConflict)
+	}
+
+	rerollURL := cardsURL + "/" + card.ID + "/reroll"
+	if code, body := doRequest(t, http.MethodPost, rerollURL, token, ""); code != http.StatusOK {
+		t.Fatalf("reroll = %d %s", code, body)
+	}
+	// The rerolled card is retired, so it cannot be rerolled again
+	if code, _ := doRequest(t, http.MethodPost, rerollURL, token, ""); code != http.StatusNotFound {
+		t.Errorf("rerolling a retired card = %d, want %d", code, http.StatusNotFound)
+	}
+
+	other := theme.userCards(user.ID)[0]
+	otherURL := cardsURL + "/" + other.ID + "/reroll"
+	if code, _ := doRequest(t, http.MethodPost, otherURL, token, ""); code != http.StatusConflict {
+		t.Errorf("reroll without rerolls left = %d, want %d", code, http.StatusConflict)
+	}
+
+	theme.setState(ThemeLocked)
+	if code, _ := doRequest(t, http.MethodPost, cardsURL, token, ""); code != http.StatusConflict {
+		t.Errorf("adding a card once locked = %d, want %d", code, http.StatusConflict)
+	}
+}
@@ -7,6 +7,8 @@ import (
 )
 
 func toggleItemHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
 	var req struct {
 		ThemeID string `json:"theme_id" param:"themeId"`
 		ItemId  string `json:"item_id" param:"itemId"`
@@ -26,22 +28,23 @@ func toggleItemHandler(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Item not found"})
 	}
 
-	item.Marked = !item.Marked
-
-	winners := theme.checkForWinners()
-	if len(winners) > 0 {
-		// Broadcast winner
-		broadcastUpdate("winners", map[string]any{
-			"cards": winners,
-		})
+	if err := startPlay(theme, user); err != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": err.Error()})
 	}
 
-	if err := saveDatabase(); err != nil {
+	before := eventState(item)
+	item.Marked = !item.Marked
+	recordEvent(EventItemToggled, user, theme.ID, item.ID, before, item)
+
+	if err := store.SaveItem(theme.ID, item); err != nil {
 		c.Logger().Error("Error saving database:", err)
 	}
 
-	// Broadcast to all WebSocket connections
-	broadcastUpdate("item_updated", item)
+	// Record and broadcast any wins this change made or undid
+	publishWins(theme, user, item.ID)
+
+	// Broadcast to the clients following this theme
+	broadcastThemeUpdate(theme.ID, "item_updated", item)
 
 	return c.JSON(http.StatusOK, map[string]string{"status": "marked"})
 }
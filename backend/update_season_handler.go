@@ -0,0 +1,67 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+)
+
+// updateSeasonHandler renames a season, changes its themes or replaces its
+// scoring rules. Fields left out of the request are kept.
+func updateSeasonHandler(c echo.Context) error {
+	user := c.Get("user").(*User)
+
+	var req struct {
+		SeasonID string        `param:"id"`
+		Name     *string       `json:"name,omitempty"`
+		ThemeIDs *[]string     `json:"theme_ids,omitempty"`
+		Scoring  *ScoringRules `json:"scoring,omitempty"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	season, found := getSeasonByID(req.SeasonID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Season not found"})
+	}
+
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Season name is required"})
+	}
+
+	if req.ThemeIDs != nil {
+		if err := validateSeasonThemes(*req.ThemeIDs); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	if req.Scoring != nil {
+		if err := req.Scoring.validate(); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	before := eventState(season)
+
+	if req.Name != nil {
+		season.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.ThemeIDs != nil {
+		season.ThemeIDs = *req.ThemeIDs
+	}
+	if req.Scoring != nil {
+		season.Scoring = req.Scoring
+	}
+
+	if err := store.SaveSeason(season); err != nil {
+		c.Logger().Error("Error saving database:", err)
+	}
+	recordEvent(EventSeasonUpdated, user, "", season.ID, before, season)
+
+	broadcastUpdate("season_updated", season)
+
+	return c.JSON(http.StatusOK, season)
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"net/http"
 	"slices"
 
@@ -16,43 +17,141 @@ func updateThemeHandler(c echo.Context) error {
 	themeID := c.Param("id")
 
 	var request struct {
-		Name        string  `json:"name"`
-		Description string  `json:"description"`
-		Items       []*Item `json:"items"`
-		IsComplete  *bool   `json:"is_complete,omitempty"` // Pointer to allow null/undefined values
+		Name                string        `json:"name"`
+		Description         string        `json:"description"`
+		Items               []*Item       `json:"items"`
+		IsComplete          *bool         `json:"is_complete,omitempty"` // Pointer to allow null/undefined values
+		GridSize            *int          `json:"grid_size,omitempty"`
+		WinPatterns         *[]WinPattern `json:"win_patterns,omitempty"`
+		MarkingMode         *string       `json:"marking_mode,omitempty"`
+		ClaimPenaltySeconds *int          `json:"claim_penalty_seconds,omitempty"`
+		MinCardDistance     *int          `json:"min_card_distance,omitempty"`
+		LayoutRules         *[]LayoutRule `json:"layout_rules,omitempty"`
+		MaxCardsPerPlayer   *int          `json:"max_cards_per_player,omitempty"`
+		MaxRerolls          *int          `json:"max_rerolls,omitempty"`
+		TeamMode            *bool         `json:"team_mode,omitempty"`
 	}
 
 	if err := c.Bind(&request); err != nil {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
 	}
 
-	if len(request.Items) < 25 {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Theme must have at least 25 items"})
+	if request.GridSize != nil {
+		if err := validateGridSize(*request.GridSize); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	if request.MarkingMode != nil {
+		if err := validateMarkingMode(*request.MarkingMode); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+	}
+
+	if request.ClaimPenaltySeconds != nil && *request.ClaimPenaltySeconds < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Claim penalty cannot be negative"})
+	}
+
+	if request.MaxCardsPerPlayer != nil && *request.MaxCardsPerPlayer < 0 || request.MaxRerolls != nil && *request.MaxRerolls < 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Card and reroll limits cannot be negative"})
 	}
 
 	// Find and update the theme
 	for i, theme := range db.Themes {
 		if theme.ID == themeID {
+			// Completion is a lifecycle transition, applied after the update
+			state := theme.State
+			if request.IsComplete != nil && *request.IsComplete != theme.IsComplete {
+				state = ThemeLive
+				if *request.IsComplete {
+					state = ThemeFinished
+				}
+				if !theme.canTransition(state) {
+					return c.JSON(http.StatusConflict, map[string]string{"error": fmt.Sprintf("cannot move theme from %s to %s", theme.State, state)})
+				}
+			}
+
+			// Cards already dealt belong to players or teams, not both
+			if request.TeamMode != nil && *request.TeamMode != theme.TeamMode && len(theme.Cards) > 0 {
+				return c.JSON(http.StatusConflict, map[string]string{"error": "Cannot change team mode after cards have been dealt"})
+			}
+
+			gridSize := theme.gridSize()
+			if request.GridSize != nil {
+				gridSize = *request.GridSize
+			}
+			if required := requiredItems(gridSize); len(request.Items) < required {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": fmt.Sprintf("Theme must have at least %d items", required)})
+			}
+
+			winPatterns := theme.WinPatterns
+			if request.WinPatterns != nil {
+				winPatterns = *request.WinPatterns
+			}
+			if err := validateWinPatterns(winPatterns, gridSize); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+
+			minCardDistance := theme.MinCardDistance
+			if request.MinCardDistance != nil {
+				minCardDistance = *request.MinCardDistance
+			}
+			if err := validateMinCardDistance(minCardDistance, gridSize); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+
+			layoutRules := theme.LayoutRules
+			if request.LayoutRules != nil {
+				layoutRules = *request.LayoutRules
+			}
+			if err := validateLayoutRules(layoutRules, gridSize); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+
+			if err := validateItemWeights(request.Items); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+			}
+
+			before := eventState(theme)
+
 			db.Themes[i].Name = request.Name
 			db.Themes[i].Description = request.Description
 			db.Themes[i].Items = request.Items
-
-			// Update completion status if provided
-			if request.IsComplete != nil {
-				db.Themes[i].IsComplete = *request.IsComplete
-
-				// If marking as incomplete and this was the active theme, keep it active
-				// If marking as complete and this is the active theme, clear the active theme
-				if *request.IsComplete && db.ActiveThemeID == themeID {
-					db.ActiveThemeID = ""
-				}
+			db.Themes[i].GridSize = gridSize
+			db.Themes[i].WinPatterns = withGridSize(winPatterns, gridSize)
+			db.Themes[i].MinCardDistance = minCardDistance
+			db.Themes[i].LayoutRules = layoutRules
+			if request.MarkingMode != nil {
+				db.Themes[i].MarkingMode = *request.MarkingMode
+			}
+			if request.MaxCardsPerPlayer != nil {
+				db.Themes[i].MaxCardsPerPlayer = *request.MaxCardsPerPlayer
+			}
+			if request.MaxRerolls != nil {
+				db.Themes[i].MaxRerolls = *request.MaxRerolls
+			}
+			if request.TeamMode != nil {
+				db.Themes[i].TeamMode = *request.TeamMode
 			}
+			if request.ClaimPenaltySeconds != nil {
+				db.Themes[i].ClaimPenaltySeconds = *request.ClaimPenaltySeconds
+			}
+
+			// Grid, items or patterns may have changed who is winning
+			publishWins(db.Themes[i], user, "")
 
-			broadcastUpdate("theme_updated", db.Themes[i])
+			broadcastUpdate("theme_updated", forPlayers(db.Themes[i]))
 
-			if err := saveDatabase(); err != nil {
+			if err := store.SaveTheme(db.Themes[i]); err != nil {
 				c.Logger().Error("Error saving database:", err)
 			}
+			recordEvent(EventThemeUpdated, user, themeID, themeID, before, db.Themes[i])
+
+			if state != theme.State {
+				if err := changeThemeState(theme, user, state); err != nil {
+					c.Logger().Error("Error changing theme state:", err)
+				}
+			}
 
 			return c.JSON(http.StatusOK, db.Themes[i])
 		}
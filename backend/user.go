@@ -8,7 +8,8 @@ type User struct {
 	Username  string    `json:"username"`
 	Avatar    string    `json:"avatar"`
 	CreatedAt time.Time `json:"created_at"`
-	IsAdmin   bool      `json:"is_admin"`
+	// Deprecated: admin access comes from Database.AdminDiscordIDs.
+	IsAdmin bool `json:"is_admin"`
 }
 
 type DiscordUser struct {
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+)
+
+// verifyCardHandler regenerates a card from the theme's seed and reports
+// whether it matches the stored card.
+func verifyCardHandler(c echo.Context) error {
+	var req struct {
+		ThemeID string `param:"id"`
+		CardID  string `param:"cardId"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	theme, found := getThemeByID(req.ThemeID)
+	if !found {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Theme not found"})
+	}
+
+	for _, card := range theme.Cards {
+		if card.ID == req.CardID {
+			return c.JSON(http.StatusOK, theme.verifyCard(card))
+		}
+	}
+
+	return c.JSON(http.StatusNotFound, map[string]string{"error": "Card not found"})
+}
@@ -1,21 +1,89 @@
 package main
 
-import "github.com/labstack/echo/v4"
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
 
+	"github.com/gorilla/websocket"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// clientSendBuffer is how many messages may wait for a client before
+	// it is dropped as too slow
+	clientSendBuffer = 256
+	// clientWriteTimeout bounds a single write to a client
+	clientWriteTimeout = 10 * time.Second
+)
+
+// client is a WebSocket connection and the room it follows, or "" for every
+// room. Broadcasts queue messages on send and the client's own goroutine
+// writes them, so a stalled client never holds up a broadcast or dbMutex.
+type client struct {
+	conn   *websocket.Conn
+	roomID string
+	send   chan []byte
+	// done is closed once the writer has stopped
+	done chan struct{}
+}
+
+// writeMessages writes queued messages until send is closed or a write
+// fails.
+func (cl *client) writeMessages() {
+	defer close(cl.done)
+
+	for message := range cl.send {
+		cl.conn.SetWriteDeadline(time.Now().Add(clientWriteTimeout))
+		if err := cl.conn.WriteMessage(websocket.TextMessage, message); err != nil {
+			// Closing the connection ends the read loop, which removes the client
+			cl.conn.Close()
+			return
+		}
+	}
+}
+
+// removeClient forgets a client. Its writer stops once the messages already
+// queued are written. Callers hold connMutex.
+func removeClient(cl *client) {
+	if connections[cl.conn] != cl {
+		return
+	}
+	delete(connections, cl.conn)
+	close(cl.send)
+}
+
+// webSocketHandler streams updates to a client. With ?room=<id> theme
+// events are limited to that room's active theme; without it the client
+// receives the events of every room.
 func webSocketHandler(c echo.Context) error {
+	roomID := c.QueryParam("room")
+	if roomID != "" {
+		dbMutex.RLock()
+		_, ok := findRoom(roomID)
+		dbMutex.RUnlock()
+		if !ok {
+			return c.JSON(http.StatusNotFound, map[string]string{"error": "Room not found"})
+		}
+	}
+
 	ws, err := upgrader.Upgrade(c.Response(), c.Request(), nil)
 	if err != nil {
 		return err
 	}
 	defer ws.Close()
 
+	cl := &client{conn: ws, roomID: roomID, send: make(chan []byte, clientSendBuffer), done: make(chan struct{})}
+	go cl.writeMessages()
+
 	connMutex.Lock()
-	connections[ws] = true
+	connections[ws] = cl
 	connMutex.Unlock()
 
 	defer func() {
 		connMutex.Lock()
-		delete(connections, ws)
+		removeClient(cl)
 		connMutex.Unlock()
 	}()
 
@@ -30,19 +98,75 @@ func webSocketHandler(c echo.Context) error {
 	return nil
 }
 
+// broadcastUpdate sends an event to every WebSocket client.
 func broadcastUpdate(eventType string, item any) {
-	connMutex.RLock()
-	defer connMutex.RUnlock()
+	broadcastTo(eventType, item, func(string) bool { return true })
+}
 
-	message := map[string]any{
+// broadcastThemeUpdate sends an event about one theme to the clients
+// watching every room and to those in the room where the theme is active.
+// Callers hold dbMutex.
+func broadcastThemeUpdate(themeID, eventType string, item any) {
+	room, ok := themeRoom(themeID)
+	broadcastTo(eventType, item, func(roomID string) bool {
+		return roomID == "" || ok && roomID == room.ID
+	})
+}
+
+// broadcastTo queues an event for the clients whose room passes include.
+// The event is encoded here, while the caller still holds dbMutex. Clients
+// too far behind to take it are disconnected.
+func broadcastTo(eventType string, item any, include func(roomID string) bool) {
+	message, err := json.Marshal(map[string]any{
 		"type": eventType,
 		"data": item,
+	})
+	if err != nil {
+		log.Println("Error encoding broadcast:", err)
+		return
+	}
+
+	connMutex.Lock()
+	defer connMutex.Unlock()
+
+	for _, cl := range connections {
+		if !include(cl.roomID) {
+			continue
+		}
+		select {
+		case cl.send <- message:
+		default:
+			log.Println("Dropping slow WebSocket client")
+			removeClient(cl)
+			cl.conn.Close()
+		}
+	}
+}
+
+// closeAllConnections writes the messages still queued for every WebSocket
+// client, then sends a close frame carrying reason and drops the connection.
+func closeAllConnections(reason string, deadline time.Time) {
+	connMutex.Lock()
+	defer connMutex.Unlock()
+
+	message := websocket.FormatCloseMessage(websocket.CloseServiceRestart, reason)
+	clients := make([]*client, 0, len(connections))
+	for _, cl := range connections {
+		clients = append(clients, cl)
+		removeClient(cl)
 	}
 
-	for conn := range connections {
-		if err := conn.WriteJSON(message); err != nil {
-			delete(connections, conn)
-			conn.Close()
+	timeout := time.NewTimer(time.Until(deadline))
+	defer timeout.Stop()
+
+	for _, cl := range clients {
+		select {
+		case <-cl.done:
+		case <-timeout.C:
+		}
+		if err := cl.conn.WriteControl(websocket.CloseMessage, message, deadline); err != nil {
+			log.Println("Error sending close frame:", err)
 		}
+		cl.conn.Close()
 	}
 }
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"cmp"
+	"log"
+	"slices"
+	"time"
+)
+
+// Win records the moment a card first satisfied one of its theme's win
+// patterns. Wins on a team's card are credited to the team. Wins are kept
+// in the order they happened. Unmarking an item revokes a win rather than
+// deleting it; satisfying the pattern again reinstates it.
+type Win struct {
+	CardID        string     `json:"card_id"`
+	UserID        string     `json:"user_id"`
+	TeamID        string     `json:"team_id,omitempty"`
+	Pattern       string     `json:"pattern"`
+	WonAt         time.Time  `json:"won_at"`
+	TriggerItemID string     `json:"trigger_item_id,omitempty"`
+	TriggeredBy   string     `json:"triggered_by,omitempty"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+	// ClaimID is the approved claim that confirmed the win, if any
+	ClaimID string `json:"claim_id,omitempty"`
+	// Players are the players credited with the win: the card's holder, or
+	// the team's members when it was won
+	Players []string `json:"players,omitempty"`
+}
+
+// RankingEntry is a card's place in the order of first wins.
+type RankingEntry struct {
+	Rank    int       `json:"rank"`
+	CardID  string    `json:"card_id"`
+	UserID  string    `json:"user_id"`
+	TeamID  string    `json:"team_id,omitempty"`
+	Players []string  `json:"players"`
+	Pattern string    `json:"pattern"`
+	WonAt   time.Time `json:"won_at"`
+}
+
+// updateWins re-evaluates every card and brings t.Wins up to date: a Win is
+// added for each pattern a card newly satisfies and wins whose pattern no
+// longer holds are revoked. itemID and actorID describe the change that
+// triggered the check.
+func (t *Theme) updateWins(actorID, itemID string, at time.Time) (won, revoked []*Win, changed []*Card) {
+	_, changed = t.checkForWinners()
+
+	type winKey struct{ cardID, pattern string }
+	existing := make(map[winKey]*Win, len(t.Wins))
+	for _, win := range t.Wins {
+		existing[winKey{win.CardID, win.Pattern}] = win
+	}
+
+	// Cards that win on the same change are ordered by age
+	cards := make([]*Card, 0, len(t.Cards))
+	for _, card := range t.Cards {
+		if card.active() {
+			cards = append(cards, card)
+		}
+	}
+	slices.SortFunc(cards, func(a, b *Card) int {
+		return cmp.Or(a.CreatedAt.Compare(b.CreatedAt), cmp.Compare(a.ID, b.ID))
+	})
+
+	for _, card := range cards {
+		for _, pattern := range card.Patterns {
+			win, ok := existing[winKey{card.ID, pattern}]
+			switch {
+			case !ok:
+				win = &Win{
+					CardID:        card.ID,
+					UserID:        card.UserID,
+					TeamID:        card.TeamID,
+					Pattern:       pattern,
+					WonAt:         at,
+					TriggerItemID: itemID,
+					TriggeredBy:   actorID,
+					Players:       slices.Clone(t.cardPlayers(card)),
+				}
+				t.Wins = append(t.Wins, win)
+				won = append(won, win)
+			case win.RevokedAt != nil:
+				win.RevokedAt = nil
+				won = append(won, win)
+			}
+		}
+	}
+
+	for _, win := range t.Wins {
+		if win.RevokedAt != nil {
+			continue
+		}
+		card, ok := t.Cards[win.CardID]
+		if ok && card.active() && slices.Contains(card.Patterns, win.Pattern) {
+			continue
+		}
+		revokedAt := at
+		win.RevokedAt = &revokedAt
+		revoked = append(revoked, win)
+	}
+
+	return won, revoked, changed
+}
+
+// winPlayers returns the players credited with win. Wins recorded before
+// players were kept fall back to the team's current members.
+func (t *Theme) winPlayers(win *Win) []string {
+	if len(win.Players) > 0 {
+		return win.Players
+	}
+	if team, ok := t.getTeam(win.TeamID); ok {
+		return team.Members
+	}
+	return []string{win.UserID}
+}
+
+// ranking orders cards by their earliest standing win.
+func (t *Theme) ranking() []RankingEntry {
+	ranking := []RankingEntry{}
+	seen := make(map[string]bool)
+
+	for _, win := range t.Wins {
+		if win.RevokedAt != nil || seen[win.CardID] {
+			continue
+		}
+		seen[win.CardID] = true
+		ranking = append(ranking, RankingEntry{
+			CardID:  win.CardID,
+			UserID:  win.UserID,
+			TeamID:  win.TeamID,
+			Players: t.winPlayers(win),
+			Pattern: win.Pattern,
+			WonAt:   win.WonAt,
+		})
+	}
+
+	// Reinstated wins keep their original time, so t.Wins is not strictly sorted
+	slices.SortStableFunc(ranking, func(a, b RankingEntry) int {
+		return a.WonAt.Compare(b.WonAt)
+	})
+	for i := range ranking {
+		ranking[i].Rank = i + 1
+	}
+
+	return ranking
+}
+
+// publishWins re-evaluates t after a change made by actor, saves whatever
+// changed and broadcasts new and revoked wins along with how close the
+// remaining players are. Callers hold dbMutex.
+func publishWins(t *Theme, actor *User, itemID string) (won []*Win) {
+	var actorID string
+	if actor != nil {
+		actorID = actor.ID
+	}
+
+	won, revoked, changed := t.updateWins(actorID, itemID, time.Now())
+
+	for _, card := range changed {
+		if err := store.SaveCard(card); err != nil {
+			log.Println("Error saving database:", err)
+		}
+	}
+	if len(won) > 0 || len(revoked) > 0 {
+		if err := store.SaveTheme(t); err != nil {
+			log.Println("Error saving database:", err)
+		}
+	}
+
+	if len(won) > 0 {
+		var cards []*Card
+		for _, win := range won {
+			if card, ok := t.Cards[win.CardID]; ok && !slices.Contains(cards, card) {
+				cards = append(cards, card)
+			}
+		}
+		broadcastThemeUpdate(t.ID, "winners", map[string]any{
+			"theme_id": t.ID,
+			"cards":    cards,
+			"wins":     won,
+		})
+	}
+	if len(revoked) > 0 {
+		broadcastThemeUpdate(t.ID, "wins_revoked", map[string]any{
+			"theme_id": t.ID,
+			"wins":     revoked,
+		})
+	}
+
+	broadcastThemeUpdate(t.ID, "near_wins", t.nearWins())
+
+	return won
+}
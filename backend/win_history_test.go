@@ -0,0 +1,91 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+// newWinHistoryTheme returns a 3x3 theme with two cards that share a first
+// row; c1 was dealt before c2.
+func newWinHistoryTheme() *Theme {
+	theme := newTestTheme("t1", 3, 9)
+	theme.setState(ThemeLive)
+	theme.Cards["c1"] = &Card{
+		ID: "c1", UserID: "u1", ThemeID: theme.ID, CreatedAt: time.Unix(1, 0),
+		Items: [][]string{{"a", "b", "c"}, {"d", freeSpaceID, "e"}, {"f", "g", "h"}},
+	}
+	theme.Cards["c2"] = &Card{
+		ID: "c2", UserID: "u2", ThemeID: theme.ID, CreatedAt: time.Unix(2, 0),
+		Items: [][]string{{"a", "b", "c"}, {"h", freeSpaceID, "g"}, {"f", "e", "d"}},
+	}
+	return theme
+}
+
+func winCardIDs(wins []*Win) []string {
+	var ids []string
+	for _, win := range wins {
+		ids = append(ids, win.CardID)
+	}
+	return ids
+}
+
+func TestUpdateWinsRecordsAndRevokes(t *testing.T) {
+	theme := newWinHistoryTheme()
+
+	markItems(t, theme, "a", "b")
+	if won, revoked, _ := theme.updateWins("admin", "b", time.Unix(10, 0)); len(won) != 0 || len(revoked) != 0 {
+		t.Fatalf("won %v and revoked %v before any line was complete", winCardIDs(won), winCardIDs(revoked))
+	}
+
+	// Both cards complete their first row on the same call; the older card comes first
+	markItems(t, theme, "c")
+	won, _, _ := theme.updateWins("admin", "c", time.Unix(11, 0))
+	if ids := winCardIDs(won); len(ids) != 2 || ids[0] != "c1" || ids[1] != "c2" {
+		t.Fatalf("won %v, want [c1 c2]", ids)
+	}
+	if won[0].TriggerItemID != "c" || won[0].TriggeredBy != "admin" {
+		t.Errorf("win not attributed to the call: %+v", won[0])
+	}
+
+	item, _ := theme.GetItem("c")
+	item.Marked = false
+	if _, revoked, _ := theme.updateWins("admin", "c", time.Unix(12, 0)); len(revoked) != 2 {
+		t.Fatalf("revoked %v, want both wins", winCardIDs(revoked))
+	}
+	if ranking := theme.ranking(); len(ranking) != 0 {
+		t.Fatalf("revoked wins still ranked: %+v", ranking)
+	}
+
+	// Both diagonals through the free space are completed by d and h
+	markItems(t, theme, "d", "h")
+	won, _, _ = theme.updateWins("admin", "h", time.Unix(13, 0))
+	if len(won) != 2 || len(theme.Wins) != 2 {
+		t.Fatalf("won %v with %d wins recorded, want both wins reinstated", winCardIDs(won), len(theme.Wins))
+	}
+	for _, win := range won {
+		if win.RevokedAt != nil || !win.WonAt.Equal(time.Unix(11, 0)) {
+			t.Errorf("reinstated win %+v, want its original time and no revocation", win)
+		}
+	}
+}
+
+func TestRanking(t *testing.T) {
+	theme := newWinHistoryTheme()
+	theme.WinPatterns = []WinPattern{{Name: "Line", Type: PatternLines}, {Name: "Blackout", Type: PatternBlackout}}
+
+	markItems(t, theme, "a", "b", "c")
+	theme.updateWins("admin", "c", time.Unix(10, 0))
+	markItems(t, theme, "d", "e", "f", "g", "h")
+	theme.updateWins("admin", "h", time.Unix(20, 0))
+
+	ranking := theme.ranking()
+	if len(ranking) != 2 {
+		t.Fatalf("got %d ranked cards, want one entry per card", len(ranking))
+	}
+	for i, want := range []string{"c1", "c2"} {
+		entry := ranking[i]
+		if entry.CardID != want || entry.Rank != i+1 || entry.Pattern != "Line" || !entry.WonAt.Equal(time.Unix(10, 0)) {
+			t.Errorf("rank %d = %+v, want %s's first win", i+1, entry, want)
+		}
+	}
+}
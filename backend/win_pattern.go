@@ -0,0 +1,167 @@
+package main
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+)
+
+// Win pattern types
+const (
+	PatternLines        = "lines"
+	PatternBlackout     = "blackout"
+	PatternFourCorners  = "four_corners"
+	PatternX            = "x"
+	PatternPlus         = "plus"
+	PatternFrame        = "frame"
+	PatternPostageStamp = "postage_stamp"
+	PatternCustom       = "custom"
+)
+
+// WinPattern is one way to win a theme. Custom patterns list their cells in
+// Mask, where bit row*GridSize+col is set for every cell that must be
+// marked. GridSize is the grid the mask was drawn for; only cards of that
+// size can win with it.
+type WinPattern struct {
+	Name     string `json:"name"`
+	Type     string `json:"type"`
+	Mask     uint64 `json:"mask,omitempty"`
+	GridSize int    `json:"grid_size,omitempty"`
+}
+
+// defaultWinPatterns is used by themes that do not list any patterns:
+// any full row, column or diagonal wins.
+var defaultWinPatterns = []WinPattern{{Name: "Line", Type: PatternLines}}
+
+// winPatterns returns the theme's patterns, falling back to the defaults.
+func (t *Theme) winPatterns() []WinPattern {
+	if len(t.WinPatterns) == 0 {
+		return defaultWinPatterns
+	}
+	return t.WinPatterns
+}
+
+// displayName returns the pattern's name, or its type when it has none.
+func (p WinPattern) displayName() string {
+	return cmp.Or(p.Name, p.Type)
+}
+
+// validateWinPatterns checks that every pattern has a known type and that
+// custom masks fit on the grid they were drawn for, which is the given size
+// unless the pattern records its own.
+func validateWinPatterns(patterns []WinPattern, size int) error {
+	for _, p := range patterns {
+		switch p.Type {
+		case PatternLines, PatternBlackout, PatternFourCorners, PatternX, PatternPlus, PatternFrame, PatternPostageStamp:
+		case PatternCustom:
+			if p.Mask == 0 {
+				return fmt.Errorf("custom pattern %q has an empty mask", p.displayName())
+			}
+			size := cmp.Or(p.GridSize, size)
+			if err := validateGridSize(size); err != nil {
+				return fmt.Errorf("custom pattern %q: %w", p.displayName(), err)
+			}
+			if p.Mask>>(size*size) != 0 {
+				return fmt.Errorf("custom pattern %q does not fit a %dx%d grid", p.displayName(), size, size)
+			}
+		default:
+			return fmt.Errorf("unknown win pattern type %q", p.Type)
+		}
+	}
+	return nil
+}
+
+// withGridSize returns a copy of patterns in which custom masks that do not
+// record a grid size are marked as drawn for the given size.
+func withGridSize(patterns []WinPattern, size int) []WinPattern {
+	sized := slices.Clone(patterns)
+	for i := range sized {
+		if sized[i].Type == PatternCustom && sized[i].GridSize == 0 {
+			sized[i].GridSize = size
+		}
+	}
+	return sized
+}
+
+func cellBit(size, row, col int) uint64 {
+	return 1 << (row*size + col)
+}
+
+// shapes returns the sets of cells that satisfy the pattern on a grid of
+// the given size; marking every cell of any one of them wins.
+func (p WinPattern) shapes(size int) []uint64 {
+	last := size - 1
+	mid := size / 2
+
+	switch p.Type {
+	case PatternLines:
+		var shapes []uint64
+		var main, anti uint64
+		for i := range size {
+			var row, col uint64
+			for j := range size {
+				row |= cellBit(size, i, j)
+				col |= cellBit(size, j, i)
+			}
+			shapes = append(shapes, row, col)
+			main |= cellBit(size, i, i)
+			anti |= cellBit(size, i, last-i)
+		}
+		return append(shapes, main, anti)
+
+	case PatternBlackout:
+		return []uint64{1<<(size*size) - 1}
+
+	case PatternFourCorners:
+		return []uint64{cellBit(size, 0, 0) | cellBit(size, 0, last) | cellBit(size, last, 0) | cellBit(size, last, last)}
+
+	case PatternX:
+		var x uint64
+		for i := range size {
+			x |= cellBit(size, i, i) | cellBit(size, i, last-i)
+		}
+		return []uint64{x}
+
+	case PatternPlus:
+		var plus uint64
+		for i := range size {
+			plus |= cellBit(size, mid, i) | cellBit(size, i, mid)
+		}
+		return []uint64{plus}
+
+	case PatternFrame:
+		var frame uint64
+		for i := range size {
+			frame |= cellBit(size, 0, i) | cellBit(size, last, i) | cellBit(size, i, 0) | cellBit(size, i, last)
+		}
+		return []uint64{frame}
+
+	case PatternPostageStamp:
+		// A 2x2 block in any corner
+		var shapes []uint64
+		for _, corner := range [][2]int{{0, 0}, {0, last - 1}, {last - 1, 0}, {last - 1, last - 1}} {
+			r, c := corner[0], corner[1]
+			shapes = append(shapes, cellBit(size, r, c)|cellBit(size, r, c+1)|cellBit(size, r+1, c)|cellBit(size, r+1, c+1))
+		}
+		return shapes
+
+	case PatternCustom:
+		// The mask's bits only line up with cards of the size it was drawn for
+		if cmp.Or(p.GridSize, size) != size || p.Mask>>(size*size) != 0 {
+			return nil
+		}
+		return []uint64{p.Mask}
+	}
+
+	return nil
+}
+
+// matches reports whether the marked cells satisfy the pattern.
+func (p WinPattern) matches(size int, marked uint64) bool {
+	for _, shape := range p.shapes(size) {
+		if marked&shape == shape {
+			return true
+		}
+	}
+	return false
+}
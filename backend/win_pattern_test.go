@@ -0,0 +1,148 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"testing"
+)
+
+// cells returns the mask of the given (row, col) cells on a size grid.
+func cells(size int, coords ...[2]int) uint64 {
+	var mask uint64
+	for _, c := range coords {
+		mask |= cellBit(size, c[0], c[1])
+	}
+	return mask
+}
+
+func TestWinPatternMatches(t *testing.T) {
+	const size = 5
+	all := uint64(1<<(size*size) - 1)
+
+	tests := []struct {
+		name    string
+		pattern WinPattern
+		marked  uint64
+		want    bool
+	}{
+		{"row", WinPattern{Type: PatternLines}, cells(size, [2]int{2, 0}, [2]int{2, 1}, [2]int{2, 2}, [2]int{2, 3}, [2]int{2, 4}), true},
+		{"anti-diagonal", WinPattern{Type: PatternLines}, cells(size, [2]int{0, 4}, [2]int{1, 3}, [2]int{2, 2}, [2]int{3, 1}, [2]int{4, 0}), true},
+		{"four of a row", WinPattern{Type: PatternLines}, cells(size, [2]int{2, 0}, [2]int{2, 1}, [2]int{2, 2}, [2]int{2, 3}), false},
+		{"corners", WinPattern{Type: PatternFourCorners}, cells(size, [2]int{0, 0}, [2]int{0, 4}, [2]int{4, 0}, [2]int{4, 4}), true},
+		{"corners are not a line", WinPattern{Type: PatternLines}, cells(size, [2]int{0, 0}, [2]int{0, 4}, [2]int{4, 0}, [2]int{4, 4}), false},
+		{"blackout", WinPattern{Type: PatternBlackout}, all, true},
+		{"blackout missing a cell", WinPattern{Type: PatternBlackout}, all &^ cellBit(size, 3, 3), false},
+		{"stamp", WinPattern{Type: PatternPostageStamp}, cells(size, [2]int{3, 3}, [2]int{3, 4}, [2]int{4, 3}, [2]int{4, 4}), true},
+		{"custom", WinPattern{Type: PatternCustom, Mask: cells(size, [2]int{1, 1}, [2]int{3, 3})}, cells(size, [2]int{1, 1}, [2]int{3, 3}, [2]int{0, 0}), true},
+	}
+
+	for _, tt := range tests {
+		if got := tt.pattern.matches(size, tt.marked); got != tt.want {
+			t.Errorf("%s: matches = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	for _, pattern := range []WinPattern{{Type: PatternLines}, {Type: PatternBlackout}, {Type: PatternFourCorners}, {Type: PatternX}, {Type: PatternPlus}, {Type: PatternFrame}, {Type: PatternPostageStamp}} {
+		if !pattern.matches(size, all) || pattern.matches(size, 0) {
+			t.Errorf("%s: full card must win and empty card must not", pattern.Type)
+		}
+	}
+}
+
+func TestValidateWinPatterns(t *testing.T) {
+	if err := validateWinPatterns([]WinPattern{{Type: PatternLines}, {Type: PatternCustom, Mask: 1 << 8}}, 3); err != nil {
+		t.Errorf("valid patterns rejected: %v", err)
+	}
+
+	for name, pattern := range map[string]WinPattern{
+		"unknown type":      {Type: "zigzag"},
+		"empty mask":        {Type: PatternCustom},
+		"mask off the grid": {Type: PatternCustom, Mask: 1 << 9},
+	} {
+		if err := validateWinPatterns([]WinPattern{pattern}, 3); err == nil {
+			t.Errorf("%s: pattern accepted", name)
+		}
+	}
+}
+
+func TestCustomPatternOnlyFitsItsGrid(t *testing.T) {
+	// The middle row of a 3x3 grid is bits 3-5, which on a 5x5 grid are
+	// scattered across the first two rows
+	middleRow := cells(3, [2]int{1, 0}, [2]int{1, 1}, [2]int{1, 2})
+	pattern := withGridSize([]WinPattern{{Name: "Middle", Type: PatternCustom, Mask: middleRow}}, 3)[0]
+
+	if !pattern.matches(3, middleRow) {
+		t.Error("3x3 card with its middle row marked does not win")
+	}
+	if pattern.matches(5, middleRow) || len(pattern.shapes(5)) != 0 {
+		t.Error("mask drawn for a 3x3 grid was applied to a 5x5 card")
+	}
+	if err := validateWinPatterns([]WinPattern{pattern}, 5); err != nil {
+		t.Errorf("pattern rejected after the grid grew: %v", err)
+	}
+	if err := validateWinPatterns([]WinPattern{{Type: PatternCustom, Mask: 1 << 9, GridSize: 3}}, 5); err == nil {
+		t.Error("mask off its own 3x3 grid accepted")
+	}
+}
+
+func TestGridSizeChangeKeepsCustomPatternLayout(t *testing.T) {
+	useTestStore(t)
+	theme := newTestTheme("theme", 3, 26)
+	theme.WinPatterns = withGridSize([]WinPattern{{Name: "Middle", Type: PatternCustom, Mask: cells(3, [2]int{1, 0}, [2]int{1, 1}, [2]int{1, 2})}}, 3)
+	db.Themes = []*Theme{theme}
+	theme.Cards["small"] = &Card{ID: "small", UserID: "u1", Items: [][]string{{"a", "b", "c"}, {"d", "e", "f"}, {"g", "h", "i"}}}
+
+	admin := &User{ID: "admin", DiscordID: "1"}
+	db.Users = []*User{admin}
+	db.AdminDiscordIDs = []string{"1"}
+	server := newTestServer(t)
+
+	items, err := json.Marshal(theme.Items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	body := fmt.Sprintf(`{"name":"theme","items":%s,"grid_size":5}`, items)
+	if status, resp := doRequest(t, http.MethodPut, server.URL+"/api/admin/themes/theme", testToken(t, admin.ID), body); status != http.StatusOK {
+		t.Fatalf("update returned %d: %s", status, resp)
+	}
+	if got := theme.WinPatterns[0].GridSize; got != 3 {
+		t.Fatalf("pattern grid size = %d after the theme grew, want 3", got)
+	}
+
+	card := theme.Cards["small"]
+	markItems(t, theme, "d", "e", "f")
+	card.checkBingo(theme)
+	if !card.IsWinner {
+		t.Error("3x3 card dealt before the grid grew does not win with its middle row")
+	}
+
+	big := &Card{ID: "big", UserID: "u2", Items: [][]string{
+		{"j", "k", "l", "d", "e"},
+		{"f", "m", "n", "o", "p"},
+		{"q", "r", "s", "t", "u"},
+		{"v", "w", "x", "y", "z"},
+		{"g", "h", "i", "a", "b"},
+	}}
+	big.checkBingo(theme)
+	if big.IsWinner {
+		t.Error("5x5 card won with the bits of a 3x3 mask")
+	}
+}
+
+func TestMigrateCustomPatternSizes(t *testing.T) {
+	d := &Database{
+		SchemaVersion: 7,
+		Themes: []*Theme{{ID: "small", GridSize: 3, WinPatterns: []WinPattern{
+			{Type: PatternLines},
+			{Type: PatternCustom, Mask: 1 << 4},
+		}}},
+	}
+
+	if _, err := migrateDatabase(d); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.Themes[0].WinPatterns; got[0].GridSize != 0 || got[1].GridSize != 3 {
+		t.Errorf("patterns after migration = %+v, want only the custom one sized 3", got)
+	}
+}
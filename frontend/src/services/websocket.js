@@ -5,13 +5,19 @@ class WebSocketService {
     this.maxReconnectAttempts = 10
     this.reconnectAttempts = 0
     this.listeners = new Map()
+    this.room = null
   }
 
-  connect() {
+  connect(room = this.room) {
+    // Remember the room so reconnects rejoin it
+    this.room = room
     try {
       // Use the same base URL as the API, but convert to WebSocket protocol
       const apiBaseUrl = import.meta.env.VITE_API_BASE_URL || 'http://localhost:8080'
-      const wsUrl = apiBaseUrl.replace(/^https?:/, apiBaseUrl.startsWith('https:') ? 'wss:' : 'ws:') + '/ws'
+      let wsUrl = apiBaseUrl.replace(/^https?:/, apiBaseUrl.startsWith('https:') ? 'wss:' : 'ws:') + '/ws'
+      if (room) {
+        wsUrl += `?room=${encodeURIComponent(room)}`
+      }
       
       console.log('Connecting to WebSocket:', wsUrl)
       this.ws = new WebSocket(wsUrl)
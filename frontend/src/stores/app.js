@@ -25,6 +25,10 @@ export const useAppStore = defineStore('app', {
     // Themes
     themes: [],
     activeThemeId: null,
+    // Latest near_wins summary for the active theme
+    nearWins: null,
+    // Room picked with ?room=<id>; without one the default room is used
+    roomId: new URLSearchParams(window.location.search).get('room'),
 
     // Server connectivity
     isServerConnected: false,
@@ -42,9 +46,19 @@ export const useAppStore = defineStore('app', {
     activeTheme: (state) => state.themes.find(theme => theme.id === state.activeThemeId),
     hasActiveTheme: (state) => !!state.activeThemeId,
     isAppReady: (state) => state.isServerConnected && !!state.token,
-    currentCard: (state) => {
+    myTeam: (state) => {
+      const theme = state.themes.find(t => t.id === state.activeThemeId)
+      return theme?.teams?.find(team => team.members.includes(state.user?.id)) || null
+    },
+    currentCard(state) {
       if (state.activeThemeId) {
-        return state.themes.find(t => t.id === state.activeThemeId)?.cards[state.user.id] || null
+        // Cards are keyed by card ID; players holding several see their first
+        // and team members see their team's
+        const cards = Object.values(state.themes.find(t => t.id === state.activeThemeId)?.cards || {})
+        const team = this.myTeam
+        return cards
+          .filter(card => (team ? card.team_id === team.id : card.user_id === state.user?.id) && !card.retired_at)
+          .sort((a, b) => a.index - b.index)[0] || null
       }
     },
     getUser: (state) => (userId) => state.users.find(user => user.id === userId) || null,
@@ -220,8 +234,10 @@ export const useAppStore = defineStore('app', {
         console.log('Theme changed via WebSocket:', data)
         this.activeThemeId = data.item // The item field contains the new theme ID
         
-        if (this.activeTheme.cards.find(card => card.user_id === this.user.id)) {
-          this.currentCard = this.activeTheme.cards.find(card => card.user_id === this.user.id)
+        const myCard = Object.values(this.activeTheme?.cards || {})
+          .find(card => card.user_id === this.user.id && !card.retired_at)
+        if (myCard) {
+          this.currentCard = myCard
         }
         
         this.showSnackbar('Admin changed the active theme!', 'info')
@@ -251,6 +267,62 @@ export const useAppStore = defineStore('app', {
         }
       })
 
+      websocketService.on('card_updated', (data) => {
+        console.log('Card updated via WebSocket:', data)
+        if (data.data) {
+          this.updateCard(data.data)
+        }
+      })
+
+      websocketService.on('item_drawn', (data) => {
+        console.log('Item drawn via WebSocket:', data)
+        if (data.data?.item) {
+          this.updateItem(data.data.item)
+          this.showSnackbar(`Called: ${data.data.item.name}`, 'info')
+        }
+      })
+
+      websocketService.on('caller_finished', (data) => {
+        console.log('Caller finished via WebSocket:', data)
+        this.showSnackbar('Every item has been called', 'info')
+      })
+
+      websocketService.on('claim_submitted', (data) => {
+        console.log('Claim submitted via WebSocket:', data)
+        if (data.data && data.data.user_id !== this.user?.id) {
+          const user = this.getUser(data.data.user_id)
+          this.showSnackbar(`${user?.username || 'A player'} called bingo!`, 'info')
+        }
+      })
+
+      websocketService.on('claim_approved', (data) => {
+        console.log('Claim approved via WebSocket:', data)
+        if (data.data) {
+          const user = this.getUser(data.data.user_id)
+          this.showSnackbar(`${user?.username || 'A player'}'s bingo was confirmed!`, 'success')
+        }
+      })
+
+      websocketService.on('claim_rejected', (data) => {
+        console.log('Claim rejected via WebSocket:', data)
+        if (data.data && data.data.user_id === this.user?.id) {
+          this.showSnackbar(`Your bingo claim was rejected: ${data.data.reason}`, 'error')
+        }
+      })
+
+      websocketService.on('card_rerolled', (data) => {
+        console.log('Card rerolled via WebSocket:', data)
+        if (data.data) {
+          this.updateCard(data.data.retired)
+          this.updateCard(data.data.card)
+        }
+      })
+
+      websocketService.on('wins_revoked', (data) => {
+        console.log('Wins revoked via WebSocket:', data)
+        this.fetchCard()
+      })
+
       websocketService.on('theme_deleted', (data) => {
         console.log('Theme deleted via WebSocket:', data)
         // Remove the theme from the local themes array
@@ -265,7 +337,37 @@ export const useAppStore = defineStore('app', {
         }
       })
 
-      websocketService.connect()
+      websocketService.on('near_wins', (data) => {
+        console.log('Near wins via WebSocket:', data)
+        if (data.data?.theme_id !== this.activeThemeId) {
+          return
+        }
+        const previous = this.nearWins?.one_away || 0
+        this.nearWins = data.data
+        if (data.data.one_away > previous) {
+          const count = data.data.one_away
+          this.showSnackbar(`${count} ${count === 1 ? 'player is' : 'players are'} one away!`, 'warning')
+        }
+      })
+
+      websocketService.on('teams_updated', (data) => {
+        console.log('Teams updated via WebSocket:', data)
+        const theme = this.themes.find(t => t.id === data.data?.theme_id)
+        if (theme) {
+          theme.teams = data.data.teams
+        }
+      })
+
+      websocketService.on('room_theme_changed', (data) => {
+        console.log('Room theme changed via WebSocket:', data)
+        if (this.roomId && data.data?.room_id === this.roomId) {
+          this.activeThemeId = data.data.active_theme_id || null
+          this.showSnackbar('Admin changed the active theme!', 'info')
+          this.fetchThemeItems()
+        }
+      })
+
+      websocketService.connect(this.roomId)
     },
 
     updateBingoCards(newCards) {
@@ -327,6 +429,10 @@ export const useAppStore = defineStore('app', {
         const response = await axios.get(`${API_BASE_URL}/api/themes`)
         this.themes = response.data.themes || []
         this.activeThemeId = response.data.active_theme_id
+        if (this.roomId) {
+          const room = await axios.get(`${API_BASE_URL}/api/rooms/${this.roomId}`)
+          this.activeThemeId = room.data.room.active_theme_id || null
+        }
         return this.themes
       } catch (error) {
         console.error('Failed to fetch themes:', error)
@@ -494,6 +600,47 @@ export const useAppStore = defineStore('app', {
       }
     },
 
+    async cloneTheme(themeId, overrides = {}) {
+      try {
+        const response = await this.apiCall(`/api/admin/themes/${themeId}/clone`, 'POST', overrides)
+        this.showSnackbar(`Theme cloned as: ${response.name}`, 'success')
+        return response
+      } catch (error) {
+        const errorMessage = error.response?.data?.error || 'Failed to clone theme'
+        this.showSnackbar(errorMessage, 'error')
+        throw error
+      }
+    },
+
+    async fetchTemplates() {
+      const response = await this.apiCall('/api/admin/templates')
+      return response.templates
+    },
+
+    async saveThemeAsTemplate(themeId, overrides = {}) {
+      try {
+        const response = await this.apiCall(`/api/admin/themes/${themeId}/template`, 'POST', overrides)
+        this.showSnackbar(`Template saved: ${response.name}`, 'success')
+        return response
+      } catch (error) {
+        const errorMessage = error.response?.data?.error || 'Failed to save template'
+        this.showSnackbar(errorMessage, 'error')
+        throw error
+      }
+    },
+
+    async instantiateTemplate(templateId, overrides = {}) {
+      try {
+        const response = await this.apiCall(`/api/admin/templates/${templateId}/instantiate`, 'POST', overrides)
+        this.showSnackbar(`Theme created from template: ${response.name}`, 'success')
+        return response
+      } catch (error) {
+        const errorMessage = error.response?.data?.error || 'Failed to create theme from template'
+        this.showSnackbar(errorMessage, 'error')
+        throw error
+      }
+    },
+
     async deleteTheme(themeId) {
       try {
         await this.apiCall(`/api/admin/themes/${themeId}`, 'DELETE')
@@ -570,6 +717,74 @@ export const useAppStore = defineStore('app', {
       }
     },
 
+    async daubItem(itemId) {
+      if (!this.activeThemeId) {
+        return
+      }
+
+      try {
+        const response = await this.apiCall(`/api/themes/${this.activeThemeId}/items/${itemId}/daub`, 'POST')
+        for (const card of response.cards) {
+          this.updateCard(card)
+        }
+        return response
+      } catch (error) {
+        this.showSnackbar(error.response?.data?.error || 'Failed to daub item', 'error')
+      }
+    },
+
+    async claimBingo() {
+      if (!this.activeThemeId) {
+        return
+      }
+
+      try {
+        const response = await this.apiCall(`/api/themes/${this.activeThemeId}/claims`, 'POST')
+        this.showSnackbar('Bingo called! Waiting for an admin to check your card', 'info')
+        return response
+      } catch (error) {
+        this.showSnackbar(error.response?.data?.error || 'Failed to call bingo', 'error')
+      }
+    },
+
+    async createTeam(name) {
+      try {
+        await this.apiCall(`/api/themes/${this.activeThemeId}/teams`, 'POST', { name })
+        await this.fetchTeamCard()
+      } catch (error) {
+        this.showSnackbar(error.response?.data?.error || 'Failed to create team', 'error')
+      }
+    },
+
+    async joinTeam(teamId) {
+      try {
+        await this.apiCall(`/api/themes/${this.activeThemeId}/teams/${teamId}/join`, 'POST')
+        await this.fetchTeamCard()
+      } catch (error) {
+        this.showSnackbar(error.response?.data?.error || 'Failed to join team', 'error')
+      }
+    },
+
+    // fetchTeamCard refreshes the active theme's teams and deals the
+    // player's team its card if it has none yet
+    async fetchTeamCard() {
+      const { teams } = await this.apiCall(`/api/themes/${this.activeThemeId}/teams`)
+      const theme = this.themes.find(t => t.id === this.activeThemeId)
+      if (theme) {
+        theme.teams = teams
+      }
+      const card = await this.apiCall(`/api/themes/${this.activeThemeId}/cards/mine`)
+      this.updateCard(card)
+    },
+
+    async leaveTeam() {
+      try {
+        await this.apiCall(`/api/themes/${this.activeThemeId}/teams/leave`, 'POST')
+      } catch (error) {
+        this.showSnackbar(error.response?.data?.error || 'Failed to leave team', 'error')
+      }
+    },
+
     updateItem(item) {
       if (this.activeThemeId) {
         const theme = this.themes.find(t => t.id === this.activeThemeId)
@@ -599,11 +814,13 @@ export const useAppStore = defineStore('app', {
       if (this.activeThemeId) {
         const theme = this.themes.find(t => t.id === this.activeThemeId)
         if (theme) {
-          theme.cards[card.user_id] = card
+          theme.cards[card.id] = card
         }
 
-        // If it's the current user's card, update currentCard
-        if (this.user && card.user_id === this.user.id) {
+        // If it's the card being shown, update currentCard
+        if (this.currentCard?.id === card.id) {
+          this.currentCard = card.retired_at ? null : card
+        } else if (!this.currentCard && this.user && card.user_id === this.user.id && !card.retired_at) {
           this.currentCard = card
         }
       }